@@ -0,0 +1,238 @@
+package api
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/apognu/n26/cli"
+)
+
+const (
+	auditSpaceTransfer = "space-transfer"
+	auditMoneyBeam     = "money-beam"
+	auditBatchTransfer = "batch-transfer"
+)
+
+// auditCommand is the command recorded in audit entries. Since the log is
+// kept forever, it must not hold any secret, so only the program name is
+// recorded unless SetAuditCommand says more.
+var auditCommand = filepath.Base(os.Args[0])
+
+// SetAuditCommand sets the command recorded in audit entries, which must
+// only include arguments that are safe to keep forever.
+func SetAuditCommand(command string) {
+	auditCommand = command
+}
+
+func recordAudit(action, recipient string, amount cli.Money, req *N26Request, message string, err error) {
+	entry := cli.AuditEntry{
+		Timestamp: time.Now().UTC(),
+		Command:   auditCommand,
+		Action:    action,
+		Recipient: recipient,
+		Amount:    amount.Amount,
+		Currency:  amount.Currency,
+		Request:   dryRun(req)[0],
+		Status:    req.StatusCode,
+		Message:   message,
+	}
+
+	if err != nil {
+		entry.Message = err.Error()
+	}
+
+	if err := appendAudit(entry); err != nil {
+		cli.Warn(err)
+	}
+}
+
+// auditHead anchors the end of the audit log, so that entries removed from
+// the end of the log can be detected. It is stored in its own file.
+type auditHead struct {
+	Count int    `json:"count"`
+	Hash  string `json:"hash"`
+}
+
+func appendAudit(entry cli.AuditEntry) error {
+	unlock, err := lockAudit()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	log, err := GetAuditLog()
+	if err != nil {
+		return err
+	}
+
+	head, err := readAuditHead()
+	if err != nil {
+		return err
+	}
+
+	if len(log) > 0 {
+		entry.PreviousHash = log[len(log)-1].Hash
+	}
+
+	// The anchor is only moved forward if it matched the log, so that a
+	// truncated log keeps failing verification after new entries.
+	anchored := head == nil || (head.Count == len(log) && head.Hash == entry.PreviousHash)
+
+	// The request body is stored as generic JSON so it serializes the same
+	// way when the entry is read back to be verified.
+	body, err := json.Marshal(entry.Request.Body)
+	if err != nil {
+		return fmt.Errorf("could not marshal audit entry")
+	}
+	entry.Request.Body = nil
+	json.Unmarshal(body, &entry.Request.Body)
+
+	entry.Hash, err = auditHash(entry)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("could not marshal audit entry")
+	}
+
+	file, err := os.OpenFile(configFile("audit"), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return fmt.Errorf("could not write audit log at '%s'", configFile("audit"))
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("could not write audit log at '%s'", configFile("audit"))
+	}
+	if err := file.Sync(); err != nil {
+		return fmt.Errorf("could not write audit log at '%s'", configFile("audit"))
+	}
+
+	if !anchored {
+		return fmt.Errorf("the audit log does not match its anchor at '%s', run 'n26 audit verify'", configFile("audit.head"))
+	}
+
+	return writeAuditHead(auditHead{Count: len(log) + 1, Hash: entry.Hash})
+}
+
+func readAuditHead() (*auditHead, error) {
+	data, err := ioutil.ReadFile(configFile("audit.head"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not read audit log anchor at '%s'", configFile("audit.head"))
+	}
+
+	var head auditHead
+	if err := json.Unmarshal(data, &head); err != nil {
+		return nil, fmt.Errorf("could not parse audit log anchor at '%s'", configFile("audit.head"))
+	}
+
+	return &head, nil
+}
+
+func writeAuditHead(head auditHead) error {
+	data, err := json.Marshal(head)
+	if err != nil {
+		return fmt.Errorf("could not marshal audit log anchor")
+	}
+
+	path := configFile("audit.head")
+	if err := writeFileAtomic(path, data); err != nil {
+		return fmt.Errorf("could not write audit log anchor at '%s'", path)
+	}
+
+	return nil
+}
+
+func auditHash(entry cli.AuditEntry) (string, error) {
+	entry.Hash = ""
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return "", fmt.Errorf("could not marshal audit entry")
+	}
+
+	return fmt.Sprintf("%x", sha256.Sum256(data)), nil
+}
+
+func GetAuditLog() (cli.AuditLog, error) {
+	log := make(cli.AuditLog, 0)
+
+	file, err := os.Open(configFile("audit"))
+	if os.IsNotExist(err) {
+		return log, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not read audit log at '%s'", configFile("audit"))
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		var entry cli.AuditEntry
+
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("could not parse entry #%d of the audit log", len(log)+1)
+		}
+
+		log = append(log, entry)
+	}
+
+	return log, scanner.Err()
+}
+
+func VerifyAuditLog() (cli.SimpleMessage, error) {
+	unlock, err := lockAudit()
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
+
+	log, err := GetAuditLog()
+	if err != nil {
+		return "", err
+	}
+
+	previous := ""
+	for idx, entry := range log {
+		hash, err := auditHash(entry)
+		if err != nil {
+			return "", err
+		}
+
+		if entry.PreviousHash != previous || entry.Hash != hash {
+			return "", fmt.Errorf("the audit log has been tampered with at entry #%d", idx+1)
+		}
+
+		previous = entry.Hash
+	}
+
+	head, err := readAuditHead()
+	if err != nil {
+		return "", err
+	}
+
+	switch {
+	case head == nil && len(log) > 0:
+		return "", fmt.Errorf("the anchor of the audit log is missing, its end cannot be verified")
+	case head == nil:
+	case len(log) < head.Count:
+		return "", fmt.Errorf("the audit log has been truncated, %d entries were expected but %d were found", head.Count, len(log))
+	case len(log) > head.Count || previous != head.Hash:
+		return "", fmt.Errorf("the audit log does not match its anchor")
+	}
+
+	return (cli.SimpleMessage)(fmt.Sprintf("The %d entries of the audit log are intact.", len(log))), nil
+}
@@ -0,0 +1,175 @@
+package api
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/apognu/n26/cli"
+)
+
+// auditHome keeps the audit log of a test in a temporary home directory.
+func auditHome(t *testing.T) {
+	home := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(home, ".config"), 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("HOME", home)
+}
+
+func auditEntry(amount cli.Amount) cli.AuditEntry {
+	return cli.AuditEntry{
+		Timestamp: time.Now().UTC(),
+		Action:    auditSpaceTransfer,
+		Amount:    amount,
+		Request:   dryRun(&N26Request{Method: "POST", Path: "/api/spaces/transaction", Body: map[string]interface{}{"amount": amount}})[0],
+		Status:    200,
+	}
+}
+
+func TestAuditLogConcurrentAppends(t *testing.T) {
+	auditHome(t)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 16)
+
+	for idx := 1; idx <= 16; idx++ {
+		wg.Add(1)
+		go func(amount cli.Amount) {
+			defer wg.Done()
+			errs <- appendAudit(auditEntry(amount))
+		}(cli.Amount(idx * 1000))
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("appendAudit() error = %v", err)
+		}
+	}
+
+	message, err := VerifyAuditLog()
+	if err != nil {
+		t.Fatalf("VerifyAuditLog() error = %v", err)
+	}
+	if !strings.Contains(string(message), "16 entries") {
+		t.Errorf("VerifyAuditLog() = %q, want 16 entries", message)
+	}
+}
+
+func TestVerifyAuditLog(t *testing.T) {
+	tests := []struct {
+		name   string
+		tamper func(t *testing.T, lines [][]byte)
+		err    string
+	}{
+		{
+			name:   "intact",
+			tamper: func(t *testing.T, lines [][]byte) {},
+		},
+		{
+			name: "changed entry",
+			tamper: func(t *testing.T, lines [][]byte) {
+				lines[1] = bytes.Replace(lines[1], []byte(`"amount":2`), []byte(`"amount":9`), 1)
+				writeAuditLines(t, lines)
+			},
+			err: "tampered with at entry #2",
+		},
+		{
+			name: "removed entry",
+			tamper: func(t *testing.T, lines [][]byte) {
+				writeAuditLines(t, append(lines[:1], lines[2:]...))
+			},
+			err: "tampered with at entry #2",
+		},
+		{
+			name: "truncated log",
+			tamper: func(t *testing.T, lines [][]byte) {
+				writeAuditLines(t, lines[:2])
+			},
+			err: "truncated",
+		},
+		{
+			name: "missing anchor",
+			tamper: func(t *testing.T, lines [][]byte) {
+				os.Remove(configFile("audit.head"))
+			},
+			err: "anchor of the audit log is missing",
+		},
+		{
+			name: "appended after truncation",
+			tamper: func(t *testing.T, lines [][]byte) {
+				writeAuditLines(t, lines[:2])
+				if err := appendAudit(auditEntry(4000)); err == nil {
+					t.Error("appendAudit() did not report the truncated log")
+				}
+			},
+			err: "does not match its anchor",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auditHome(t)
+
+			for _, amount := range []cli.Amount{1000, 2000, 3000} {
+				if err := appendAudit(auditEntry(amount)); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			data, err := ioutil.ReadFile(configFile("audit"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			tt.tamper(t, bytes.Split(bytes.TrimSpace(data), []byte("\n")))
+
+			_, err = VerifyAuditLog()
+			if tt.err == "" && err != nil {
+				t.Errorf("VerifyAuditLog() error = %v", err)
+			}
+			if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+				t.Errorf("VerifyAuditLog() error = %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func writeAuditLines(t *testing.T, lines [][]byte) {
+	data := append(bytes.Join(lines, []byte("\n")), '\n')
+	if err := ioutil.WriteFile(configFile("audit"), data, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAuditCommand(t *testing.T) {
+	auditHome(t)
+
+	defer func(command string, args []string) {
+		auditCommand, os.Args = command, args
+	}(auditCommand, os.Args)
+
+	os.Args = []string{"n26", "--password-cmd", "echo secret", "transfer", "john@example.com", "10"}
+	SetAuditCommand("n26 transfer --yes")
+
+	req := &N26Request{Method: "POST", Path: "/api/transactions", StatusCode: 200}
+	recordAudit(auditMoneyBeam, "john@example.com", cli.Money{Amount: 10000, Currency: "EUR"}, req, "Your transfer has been requested.", nil)
+
+	log, err := GetAuditLog()
+	if err != nil || len(log) != 1 {
+		t.Fatalf("GetAuditLog() = (%v, %v), want one entry", log, err)
+	}
+
+	if log[0].Command != "n26 transfer --yes" {
+		t.Errorf("the audit log recorded the command %q, want %q", log[0].Command, "n26 transfer --yes")
+	}
+}
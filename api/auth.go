@@ -0,0 +1,93 @@
+package api
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/apognu/n26/cli"
+)
+
+func Login() (cli.SimpleMessage, error) {
+	if _, err := login(oauthConfig()); err != nil {
+		return "", err
+	}
+
+	return (cli.SimpleMessage)(fmt.Sprintf("You are now logged in with profile '%s'.", profile)), nil
+}
+
+func Logout() (cli.SimpleMessage, error) {
+	creds, err := LoadCredentials()
+	if err == errWrongPassphrase {
+		return "", err
+	}
+	if err != nil {
+		return "", fmt.Errorf("you are not logged in with profile '%s'", profile)
+	}
+
+	if err := revokeToken(creds.RefreshToken); err != nil {
+		cli.Warn(fmt.Errorf("could not revoke the refresh token upstream, it will expire on its own"))
+	}
+
+	DeleteCredentials()
+	clearSessionKey()
+
+	return (cli.SimpleMessage)(fmt.Sprintf("You have been logged out of profile '%s'.", profile)), nil
+}
+
+func GetAuthStatus() (*cli.AuthStatus, error) {
+	status := &cli.AuthStatus{Profile: profile, DeviceToken: DeviceToken()}
+
+	creds, err := LoadCredentials()
+	status.Store = storeName
+	if err == errWrongPassphrase {
+		return nil, err
+	}
+	if err != nil {
+		return status, nil
+	}
+
+	status.LoggedIn = true
+	status.TokenType = creds.TokenType
+	status.Expiry = creds.Expiry
+	status.Refreshable = creds.RefreshToken != ""
+	status.RefreshExpiry = creds.RefreshExpiry
+
+	return status, nil
+}
+
+func revokeToken(token string) error {
+	form := url.Values{}
+	form.Set("token", token)
+	form.Set("token_type_hint", "refresh_token")
+
+	resp, err := authRequest("/oauth/revoke", "application/x-www-form-urlencoded", strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode > 399 {
+		return fmt.Errorf("token revocation failed with status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// authRequest performs a request to the authentication endpoints, which are
+// authenticated with the client credentials instead of a token.
+func authRequest(path, contentType string, body io.Reader) (*http.Response, error) {
+	c := oauthConfig()
+
+	req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%s%s", baseURL, path), body)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", contentType)
+	req.SetBasicAuth(c.ClientID, c.ClientSecret)
+
+	return httpClient().Do(req)
+}
@@ -0,0 +1,259 @@
+package api
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/apognu/n26/cli"
+)
+
+func (cl *N26Client) CreateBatchTransfer(meta *cli.Metadata, file, id string, restart bool) (cli.Printable, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("could not read batch file '%s'", file)
+	}
+
+	balance, err := cl.GetBalance(meta)
+	if err != nil {
+		return nil, fmt.Errorf("could not get current balance")
+	}
+
+	batch, err := parseBatch(file, data, balance.Currency)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err = batchID(file, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// The journal is locked from the moment it is read until every transfer
+	// is recorded.
+	unlock, err := lockBatch(id)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	path, err := journalPath(file, id)
+	if err != nil {
+		return nil, err
+	}
+
+	journal, err := openJournal(path, restart)
+	if err != nil {
+		return nil, err
+	}
+
+	indexes, err := journal.Remaining(batch)
+	if err != nil {
+		return nil, err
+	}
+
+	remaining := make(cli.BatchTransferList, 0, len(indexes))
+	details := make([]cli.MoneyBeamDetails, 0, len(indexes))
+
+	for _, idx := range indexes {
+		trx := batch[idx]
+
+		d, err := batchTransferDetails(trx)
+		if err != nil {
+			return nil, fmt.Errorf("transfer #%d: %s", idx+1, err)
+		}
+
+		if d.PartnerIBAN == "" && !cl.CheckContact(trx.Recipient) {
+			return nil, fmt.Errorf("transfer #%d: the provided recipient ID is not associated with an N26 account", idx+1)
+		}
+
+		remaining = append(remaining, trx)
+		details = append(details, d)
+	}
+
+	if remaining.Total() > balance.UsageBalance {
+		return nil, fmt.Errorf("the batch total of %s exceeds your usable balance of %s", cli.Curr(remaining.Total(), balance.Currency), cli.Curr(balance.UsageBalance, balance.Currency))
+	}
+
+	if err := checkLimits(meta, remaining, true, balance.Currency); err != nil {
+		return nil, err
+	}
+
+	requests := make([]*N26Request, len(details))
+	for i, d := range details {
+		requests[i] = &N26Request{
+			Method: http.MethodPost,
+			Path:   "/api/transactions",
+			Body:   cli.MoneyBeam{Transaction: d},
+		}
+	}
+
+	cli.ConfirmBatchTransfer(meta, remaining, balance)
+	confirmAmounts(meta, remaining, balance.Currency)
+
+	pin, err := readPIN(meta)
+	if err != nil {
+		return nil, err
+	}
+
+	if meta.DryRun {
+		return dryRun(requests...), nil
+	}
+
+	for i, idx := range indexes {
+		req := requests[i]
+		req.Body = cli.MoneyBeam{PIN: pin, Transaction: details[i]}
+
+		if err := journal.Record(idx, remaining[i], journalPending); err != nil {
+			return nil, err
+		}
+
+		_, err := cl.Request(req, false)
+
+		recordAudit(auditBatchTransfer, remaining[i].Recipient, cli.Money{Amount: remaining[i].Amount, Currency: balance.Currency}, req, fmt.Sprintf("Transfer #%d of batch '%s' has been requested.", idx+1, file), err)
+
+		if err != nil {
+			// Only client errors guarantee the transfer was not performed,
+			// anything else is left pending to be checked manually.
+			if req.StatusCode >= 400 && req.StatusCode < 500 {
+				if jerr := journal.Record(idx, remaining[i], journalFailed); jerr != nil {
+					return nil, fmt.Errorf("transfer #%d failed after %d of %d transfers: %s (%s)", idx+1, i, len(indexes), err, jerr)
+				}
+			}
+
+			return nil, fmt.Errorf("transfer #%d failed after %d of %d transfers: %s", idx+1, i, len(indexes), err)
+		}
+
+		if err := journal.Record(idx, remaining[i], journalDone); err != nil {
+			return nil, err
+		}
+	}
+
+	return (cli.SimpleMessage)(fmt.Sprintf("Your %d transfers totalling %s have been requested, please confirm them from your paired device.", len(remaining), cli.Curr(remaining.Total(), balance.Currency))), nil
+}
+
+func parseBatch(file string, data []byte, currency string) (cli.BatchTransferList, error) {
+	batch := make(cli.BatchTransferList, 0)
+
+	if strings.HasSuffix(strings.ToLower(file), ".json") {
+		rows := make([]batchRow, 0)
+		if err := json.Unmarshal(data, &rows); err != nil {
+			return nil, fmt.Errorf("could not parse batch file '%s'", file)
+		}
+
+		// Amounts are parsed as in CSV files, so that both formats refuse
+		// the same amounts instead of rounding them silently.
+		for idx, row := range rows {
+			amount, err := cli.ParseMoney(strings.Trim(string(row.Amount), `"`), currency)
+			if err != nil {
+				return nil, fmt.Errorf("transfer #%d: %s", idx+1, err)
+			}
+
+			batch = append(batch, cli.BatchTransfer{Recipient: row.Recipient, Name: row.Name, BIC: row.BIC, Amount: amount, Reference: row.Reference})
+		}
+	} else {
+		reader := csv.NewReader(bytes.NewReader(data))
+		reader.FieldsPerRecord = -1
+		reader.TrimLeadingSpace = true
+
+		rows, err := reader.ReadAll()
+		if err != nil {
+			return nil, fmt.Errorf("could not parse batch file '%s'", file)
+		}
+
+		for idx, row := range rows {
+			if idx == 0 && isBatchHeader(row) {
+				continue
+			}
+
+			if len(row) < 3 {
+				return nil, fmt.Errorf("line %d: expected at least a recipient, an amount and a reference", idx+1)
+			}
+
+			amount, err := cli.ParseMoney(row[1], currency)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %s", idx+1, err)
+			}
+
+			trx := cli.BatchTransfer{Recipient: row[0], Amount: amount, Reference: row[2]}
+			if len(row) > 3 {
+				trx.Name = row[3]
+			}
+			if len(row) > 4 {
+				trx.BIC = row[4]
+			}
+
+			batch = append(batch, trx)
+		}
+	}
+
+	if len(batch) == 0 {
+		return nil, fmt.Errorf("the batch file '%s' does not contain any transfer", file)
+	}
+
+	return batch, nil
+}
+
+// batchRow is a transfer of a JSON batch file, whose amount is kept as
+// written until it is validated.
+type batchRow struct {
+	Recipient string          `json:"recipient"`
+	Name      string          `json:"name"`
+	BIC       string          `json:"bic"`
+	Amount    json.RawMessage `json:"amount"`
+	Reference string          `json:"reference"`
+}
+
+// batchColumns are the columns of a CSV batch file, in order.
+var batchColumns = []string{"recipient", "amount", "reference", "name", "bic"}
+
+// isBatchHeader tells whether a CSV row names the columns of the batch file
+// instead of describing a transfer.
+func isBatchHeader(row []string) bool {
+	if len(row) < 3 || len(row) > len(batchColumns) {
+		return false
+	}
+
+	for idx, column := range row {
+		if !strings.EqualFold(strings.TrimSpace(column), batchColumns[idx]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func batchTransferDetails(trx cli.BatchTransfer) (cli.MoneyBeamDetails, error) {
+	details := cli.MoneyBeamDetails{Type: "FT", PartnerName: trx.Name, Amount: trx.Amount, Comment: trx.Reference}
+
+	if trx.Amount <= 0 {
+		return details, fmt.Errorf("the amount must be positive")
+	}
+
+	switch {
+	case strings.Contains(trx.Recipient, "@"):
+		details.PartnerEmail = trx.Recipient
+	case strings.HasPrefix(trx.Recipient, "+"):
+		details.PartnerPhone = trx.Recipient
+	case isIBAN(trx.Recipient):
+		if trx.Name == "" {
+			return details, fmt.Errorf("a name is required for IBAN transfers")
+		}
+
+		details.Type = "DT"
+		details.PartnerIBAN = normalizeIBAN(trx.Recipient)
+		details.PartnerBIC = trx.BIC
+	default:
+		return details, fmt.Errorf("the recipient must be an email address, a phone number (starting with '+') or a valid IBAN")
+	}
+
+	if details.PartnerName == "" {
+		details.PartnerName = trx.Recipient
+	}
+
+	return details, nil
+}
@@ -0,0 +1,51 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/apognu/n26/cli"
+)
+
+func TestParseBatchAmounts(t *testing.T) {
+	tests := []struct {
+		name     string
+		csv      string
+		json     string
+		currency string
+		amount   cli.Amount
+		err      bool
+	}{
+		{name: "cents", csv: "10.05", json: `10.05`, currency: "EUR", amount: 10050},
+		{name: "quoted", csv: `"10.05"`, json: `"10.05"`, currency: "EUR", amount: 10050},
+		{name: "whole", csv: "10", json: `10`, currency: "EUR", amount: 10000},
+		{name: "sub-cent", csv: "10.0049", json: `10.0049`, currency: "EUR", err: true},
+		{name: "half a cent", csv: "10.005", json: `10.005`, currency: "EUR", err: true},
+		{name: "fils", csv: "10.005", json: `10.005`, currency: "KWD", amount: 10005},
+		{name: "sub-yen", csv: "10.5", json: `10.5`, currency: "JPY", err: true},
+		{name: "not a number", csv: "ten", json: `"ten"`, currency: "EUR", err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files := map[string]string{
+				"batch.csv":  "john@example.com," + tt.csv + ",March invoice\n",
+				"batch.json": `[{"recipient":"john@example.com","amount":` + tt.json + `,"reference":"March invoice"}]`,
+			}
+
+			for file, data := range files {
+				batch, err := parseBatch(file, []byte(data), tt.currency)
+
+				if tt.err {
+					if err == nil {
+						t.Errorf("parseBatch(%s) = %v, want an error", file, batch)
+					}
+					continue
+				}
+
+				if err != nil || len(batch) != 1 || batch[0].Amount != tt.amount {
+					t.Errorf("parseBatch(%s) = (%v, %v), want an amount of %d", file, batch, err, tt.amount)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package api
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/apognu/n26/cli"
+)
+
+// GetBudgetStatus compares the monthly budgets defined in the configuration
+// file, keyed by category ID, to the expenses of the current month.
+func (cl *N26Client) GetBudgetStatus(meta *cli.Metadata) (*cli.BudgetStatus, error) {
+	budgets := meta.GetBudgets()
+	if len(budgets) == 0 {
+		return nil, fmt.Errorf("no budget is defined in the configuration file")
+	}
+
+	if meta.GetCategories() == nil {
+		return nil, fmt.Errorf("could not get categories")
+	}
+
+	now := time.Now().In(cli.Location)
+	period, err := parsePeriod("this-month", "this-month", now)
+	if err != nil {
+		return nil, err
+	}
+
+	stats, err := cl.getStatistics(period)
+	if err != nil {
+		return nil, err
+	}
+
+	spent := make(map[string]cli.Amount)
+	for _, m := range stats.Movements {
+		spent[m.Category] += m.Expense
+	}
+
+	status := &cli.BudgetStatus{
+		Categories: meta.Categories,
+		Month:      period.From,
+		Day:        now.Day(),
+		Days:       period.To.Day(),
+	}
+
+	for category, limit := range budgets {
+		if _, ok := meta.Categories[category]; !ok {
+			return nil, fmt.Errorf("unknown category '%s' in the budgets of the configuration file", category)
+		}
+
+		status.Budgets = append(status.Budgets, cli.Budget{Category: category, Limit: limit, Spent: spent[category]})
+	}
+
+	sort.Slice(status.Budgets, func(i, j int) bool {
+		return meta.Categories[status.Budgets[i].Category] < meta.Categories[status.Budgets[j].Category]
+	})
+
+	return status, nil
+}
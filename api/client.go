@@ -9,20 +9,30 @@ import (
 	"net/http"
 	"os"
 	"runtime"
+	"sync"
 	"time"
 
 	"github.com/apognu/n26/cli"
 	"golang.org/x/oauth2"
 )
 
-type N26Client http.Client
+// N26Client is safe for concurrent use. All requests share the same token
+// source, which is replaced when N26 refuses the current token.
+type N26Client struct {
+	http   *http.Client
+	config oauth2.Config
+
+	mu     sync.Mutex
+	source oauth2.TokenSource
+}
 
 type N26Request struct {
-	Method  string
-	Path    string
-	Params  map[string]string
-	Body    interface{}
-	Decoder *JSON
+	Method     string
+	Path       string
+	Params     map[string]string
+	Body       interface{}
+	Decoder    *JSON
+	StatusCode int
 }
 
 type N26Error struct {
@@ -33,48 +43,148 @@ type N26Error struct {
 type CredentialsExpiry time.Time
 
 type Credentials struct {
-	TokenType    string    `json:"token_type"`
-	AccessToken  string    `json:"access_token"`
-	RefreshToken string    `json:"refresh_token"`
-	Expiry       time.Time `json:"expiry"`
+	TokenType     string    `json:"token_type"`
+	AccessToken   string    `json:"access_token"`
+	RefreshToken  string    `json:"refresh_token"`
+	Expiry        time.Time `json:"expiry"`
+	RefreshExpiry time.Time `json:"refresh_expiry,omitempty"`
+}
+
+// Token returns the stored token, considered expired refreshMargin before it
+// actually expires.
+func (creds *Credentials) Token() *oauth2.Token {
+	token := &oauth2.Token{
+		TokenType:    creds.TokenType,
+		AccessToken:  creds.AccessToken,
+		RefreshToken: creds.RefreshToken,
+	}
+
+	if !creds.Expiry.IsZero() {
+		token.Expiry = creds.Expiry.Add(-refreshMargin)
+	}
+
+	return token
 }
 
-const (
-	baseURL = "https://api.tech26.de"
-	// baseURL = "http://127.0.0.1:10000"
+// savedCredentials are the credentials last read from or written to the
+// store, which requests running in parallel may both update.
+var (
+	savedCredentials   []byte
+	savedCredentialsMu sync.Mutex
 )
 
-func NewClient() (*N26Client, error) {
-	c := oauth2.Config{
+func getSavedCredentials() []byte {
+	savedCredentialsMu.Lock()
+	defer savedCredentialsMu.Unlock()
+
+	return savedCredentials
+}
+
+func setSavedCredentials(data []byte) {
+	savedCredentialsMu.Lock()
+	defer savedCredentialsMu.Unlock()
+
+	savedCredentials = data
+}
+
+// baseURL is the root of the N26 API, which can be pointed at another server
+// for testing.
+var baseURL = "https://api.tech26.de"
+
+func oauthConfig() oauth2.Config {
+	return oauth2.Config{
 		Endpoint:     oauth2.Endpoint{TokenURL: fmt.Sprintf("%s/oauth/token", baseURL)},
 		ClientID:     "android",
 		ClientSecret: "secret",
 	}
+}
+
+func NewClient() (*N26Client, error) {
+	c := oauthConfig()
 
 	var token *oauth2.Token
 	if creds, err := LoadCredentials(); err == nil {
-		token = &oauth2.Token{TokenType: creds.TokenType, AccessToken: creds.AccessToken, RefreshToken: creds.RefreshToken, Expiry: creds.Expiry}
-		if !token.Valid() {
-			token = &oauth2.Token{RefreshToken: creds.RefreshToken}
-		}
+		token = creds.Token()
+		warnExpiry(creds)
+	} else if err == errWrongPassphrase {
+		return nil, err
 	} else {
-		username := cli.ReadLine("N26 email address:")
-		password, err := cli.ReadSecret("N26 password:")
+		token, err = login(c)
 		if err != nil {
-			return nil, fmt.Errorf("could not read password")
+			return nil, err
 		}
+	}
 
-		token, err = c.PasswordCredentialsToken(oauth2.NoContext, username, string(password))
-		if err != nil {
+	cl := &N26Client{
+		http:   httpClient(),
+		config: c,
+		source: oauth2.ReuseTokenSource(token, &sharedTokenSource{config: c}),
+	}
+
+	return cl, nil
+}
+
+func (cl *N26Client) Token() (*oauth2.Token, error) {
+	cl.mu.Lock()
+	source := cl.source
+	cl.mu.Unlock()
+
+	return source.Token()
+}
+
+// reauthenticate replaces the token source after N26 refused a token, unless
+// another request already did so.
+func (cl *N26Client) reauthenticate(refused *oauth2.Token) error {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+
+	if current, err := cl.source.Token(); err == nil && current.AccessToken != refused.AccessToken {
+		return nil
+	}
+
+	expireCredentials(refused.AccessToken)
+
+	var token *oauth2.Token
+	if _, err := LoadCredentials(); err == errWrongPassphrase {
+		return err
+	} else if err != nil {
+		if token, err = login(cl.config); err != nil {
+			return err
+		}
+	}
+
+	cl.source = oauth2.ReuseTokenSource(token, &sharedTokenSource{config: cl.config})
+
+	return nil
+}
+
+func login(c oauth2.Config) (*oauth2.Token, error) {
+	username, password, err := credentialsProvider()
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := c.PasswordCredentialsToken(authContext(), username, password)
+	if err != nil {
+		mfaToken, ok := mfaRequired(err)
+		if !ok {
+			return nil, err
+		}
+
+		if token, err = mfaLogin(mfaToken); err != nil {
 			return nil, err
 		}
+	}
 
-		token, _ = c.TokenSource(oauth2.NoContext, token).Token()
+	token, _ = c.TokenSource(authContext(), token).Token()
 
-		SaveCredentials(token, time.Now().Add(50*time.Minute))
+	unlock, err := lockCredentials()
+	if err != nil {
+		return nil, err
 	}
+	defer unlock()
 
-	return (*N26Client)(c.Client(oauth2.NoContext, token)), nil
+	return SaveCredentials(token, tokenExpiry(token)).Token(), nil
 }
 
 func (cl *N26Client) Request(r *N26Request, retry bool) (interface{}, error) {
@@ -101,17 +211,25 @@ func (cl *N26Client) Request(r *N26Request, retry bool) (interface{}, error) {
 		req.Header.Set("Content-Type", "application/json")
 	}
 
-	resp, err := (*http.Client)(cl).Do(req)
+	token, err := cl.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	token.SetAuthHeader(req)
+
+	resp, err := cl.http.Do(req)
 	if err != nil {
 		return nil, err
 	}
 
+	r.StatusCode = resp.StatusCode
+
 	if resp.StatusCode == http.StatusUnauthorized {
-		ExpireCredentials()
+		resp.Body.Close()
 
 		if !retry {
-			cl, err := NewClient()
-			if err == nil {
+			if err := cl.reauthenticate(token); err == nil {
 				return cl.Request(r, true)
 			}
 		}
@@ -131,9 +249,7 @@ func (cl *N26Client) Request(r *N26Request, retry bool) (interface{}, error) {
 	}
 
 	if resp.StatusCode > 399 {
-		r.Decoder = NewJSON(new(N26Error))
-
-		output, err := r.Decoder.Decode(resp.Body)
+		output, err := NewJSON(new(N26Error)).Decode(resp.Body)
 		if err != nil {
 			return nil, fmt.Errorf("an unknown error has occured")
 		}
@@ -144,35 +260,55 @@ func (cl *N26Client) Request(r *N26Request, retry bool) (interface{}, error) {
 		return nil, nil
 	}
 
-	output, err := r.Decoder.Decode(resp.Body)
+	return r.Decoder.Decode(resp.Body)
+}
+
+func ConfigPath() string {
+	return configFile("auth")
+}
 
-	c := cl.Transport.(*oauth2.Transport)
-	newToken, err := c.Source.Token()
-	if err == nil {
-		SaveCredentials(newToken, newToken.Expiry)
+// The default profile keeps its files next to each other in the
+// configuration directory, other profiles have a directory of their own.
+func configFile(name string) string {
+	if profile != defaultProfile {
+		return fmt.Sprintf("%s/%s", profileDir(profile), name)
 	}
+	return globalFile(name)
+}
 
-	return output, err
+func globalFile(name string) string {
+	switch runtime.GOOS {
+	case "linux":
+		return fmt.Sprintf("%s/.config/n26.%s", os.Getenv("HOME"), name)
+	case "darwin":
+		return fmt.Sprintf("%s/.n26.%s", os.Getenv("HOME"), name)
+	default:
+		cli.Fatal(fmt.Errorf("platform '%s' unsupported", runtime.GOOS))
+	}
+	return ""
 }
 
-func ConfigPath() string {
+func profileDir(name string) string {
 	switch runtime.GOOS {
 	case "linux":
-		return fmt.Sprintf("%s/.config/n26.auth", os.Getenv("HOME"))
+		return fmt.Sprintf("%s/.config/n26/%s", os.Getenv("HOME"), name)
 	case "darwin":
-		return fmt.Sprintf("%s/.n26.auth", os.Getenv("HOME"))
+		return fmt.Sprintf("%s/.n26/%s", os.Getenv("HOME"), name)
 	default:
 		cli.Fatal(fmt.Errorf("platform '%s' unsupported", runtime.GOOS))
 	}
 	return ""
 }
 
-func SaveCredentials(token *oauth2.Token, exp time.Time) {
-	creds := Credentials{
-		TokenType:    token.TokenType,
-		AccessToken:  token.AccessToken,
-		RefreshToken: token.RefreshToken,
-		Expiry:       exp,
+// SaveCredentials stores a token expiring at the given time, and returns the
+// credentials it was stored as.
+func SaveCredentials(token *oauth2.Token, exp time.Time) *Credentials {
+	creds := &Credentials{
+		TokenType:     token.TokenType,
+		AccessToken:   token.AccessToken,
+		RefreshToken:  token.RefreshToken,
+		Expiry:        exp,
+		RefreshExpiry: refreshExpiry(token),
 	}
 
 	data, err := json.Marshal(creds)
@@ -180,16 +316,27 @@ func SaveCredentials(token *oauth2.Token, exp time.Time) {
 		cli.Fatal(fmt.Errorf("could not marshal credentials"))
 	}
 
-	err = ioutil.WriteFile(ConfigPath(), data, 0600)
-	if err != nil {
-		cli.Fatal(fmt.Errorf("could not write credentials file to '%s'", ConfigPath()))
+	// Avoid writing to the store when the token did not change
+	if bytes.Equal(data, getSavedCredentials()) {
+		return creds
+	}
+
+	if err := credentialStore().Save(data); err != nil {
+		cli.Fatal(err)
 	}
+
+	setSavedCredentials(data)
+
+	return creds
 }
 
 func LoadCredentials() (*Credentials, error) {
-	data, err := ioutil.ReadFile(ConfigPath())
+	data, err := credentialStore().Load()
 	if err != nil {
-		return nil, err
+		var merr error
+		if data, merr = migrateCredentials(); merr != nil {
+			return nil, err
+		}
 	}
 
 	creds := new(Credentials)
@@ -198,20 +345,43 @@ func LoadCredentials() (*Credentials, error) {
 		return nil, err
 	}
 
+	setSavedCredentials(bytes.TrimSpace(data))
+
 	return creds, nil
 }
 
 func DeleteCredentials() {
-	err := os.Remove(ConfigPath())
-	if err != nil {
-		cli.Fatal(fmt.Errorf("could not delete credentials file at '%s'", ConfigPath()))
+	setSavedCredentials(nil)
+
+	if err := credentialStore().Delete(); err != nil {
+		cli.Fatal(err)
 	}
 }
 
 func ExpireCredentials() {
+	expireCredentials("")
+}
+
+// expireCredentials marks the stored token as expired, unless another process
+// already replaced the token that was refused.
+func expireCredentials(refused string) {
+	unlock, err := lockCredentials()
+	if err != nil {
+		cli.Fatal(err)
+	}
+	defer unlock()
+
 	creds, err := LoadCredentials()
+	if err == errWrongPassphrase {
+		cli.Fatal(err)
+	}
 	if err != nil {
-		DeleteCredentials()
+		credentialStore().Delete()
+		return
+	}
+
+	if refused != "" && creds.AccessToken != refused {
+		return
 	}
 
 	SaveCredentials(&oauth2.Token{
@@ -0,0 +1,137 @@
+package api
+
+import (
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+func TestConcurrentRequestsShareDeviceToken(t *testing.T) {
+	auditHome(t)
+
+	var (
+		mu     sync.Mutex
+		tokens = make(map[string]int)
+	)
+
+	fakeN26(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		tokens[r.Header.Get("device-token")]++
+		mu.Unlock()
+
+		reply(w, 200, `{}`)
+	})
+
+	// The device token is resolved by the first request, as on a fresh
+	// installation.
+	deviceToken = ""
+
+	cl := &N26Client{
+		http:   httpClient(),
+		config: oauthConfig(),
+		source: oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "access", Expiry: time.Now().Add(time.Hour)}),
+	}
+
+	jobs := make([]func() error, 64)
+	for idx := range jobs {
+		jobs[idx] = func() error {
+			_, err := cl.Request(&N26Request{Method: http.MethodGet, Path: "/api/me"}, false)
+			return err
+		}
+	}
+
+	if err := Parallel(len(jobs), jobs...); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := ioutil.ReadFile(globalFile("device"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(tokens) != 1 || tokens[strings.TrimSpace(string(data))] != len(jobs) {
+		t.Errorf("requests were sent with device tokens %v, want %d requests with %s", tokens, len(jobs), data)
+	}
+}
+
+func TestConcurrentCredentials(t *testing.T) {
+	t.Setenv("XDG_RUNTIME_DIR", "")
+
+	defer func(p string, provided bool) {
+		passphrase, passphraseProvided = p, provided
+	}(passphrase, passphraseProvided)
+	defer resetCredentialStore()
+	defer setSavedCredentials(nil)
+
+	SetPassphrase("passphrase")
+	SetCredentialStore(&encryptedFileStore{path: filepath.Join(t.TempDir(), "auth")})
+
+	SaveCredentials(&oauth2.Token{AccessToken: "access", RefreshToken: "refresh"}, time.Now())
+
+	jobs := make([]func() error, 32)
+	for idx := range jobs {
+		if idx%2 == 0 {
+			jobs[idx] = func() error {
+				_, err := LoadCredentials()
+				return err
+			}
+			continue
+		}
+
+		jobs[idx] = func() error {
+			SaveCredentials(&oauth2.Token{AccessToken: "access", RefreshToken: "refresh"}, time.Now())
+			return nil
+		}
+	}
+
+	if err := Parallel(len(jobs), jobs...); err != nil {
+		t.Fatal(err)
+	}
+
+	creds, err := LoadCredentials()
+	if err != nil || creds.RefreshToken != "refresh" {
+		t.Errorf("LoadCredentials() = (%+v, %v)", creds, err)
+	}
+}
+
+func TestRefreshedTokenExpiresEarly(t *testing.T) {
+	auditHome(t)
+	t.Setenv("XDG_RUNTIME_DIR", "")
+
+	defer func(p string, provided bool) {
+		passphrase, passphraseProvided = p, provided
+	}(passphrase, passphraseProvided)
+	defer resetCredentialStore()
+	defer setSavedCredentials(nil)
+
+	SetPassphrase("passphrase")
+	SetCredentialStore(&encryptedFileStore{path: filepath.Join(t.TempDir(), "auth")})
+
+	fakeN26(t, func(w http.ResponseWriter, r *http.Request) {
+		reply(w, 200, mfaTokenBody)
+	})
+
+	SaveCredentials(&oauth2.Token{AccessToken: "expired", RefreshToken: "refresh"}, time.Now())
+
+	source := &sharedTokenSource{config: oauthConfig()}
+
+	for _, refresh := range []string{"first", "second"} {
+		token, err := source.Token()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// The fake server issues tokens valid for 30 minutes.
+		if expiry := time.Until(token.Expiry); expiry > 30*time.Minute-refreshMargin {
+			t.Errorf("the %s refreshed token expires in %s, want at most %s", refresh, expiry, 30*time.Minute-refreshMargin)
+		}
+
+		SaveCredentials(token, time.Now())
+	}
+}
@@ -0,0 +1,71 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/apognu/n26/cli"
+)
+
+var config = new(cli.Config)
+
+func LoadConfig() (*cli.Config, error) {
+	conf := new(cli.Config)
+
+	data, err := ioutil.ReadFile(configFile("config"))
+	if os.IsNotExist(err) {
+		config = conf
+		return conf, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not read configuration file at '%s'", configFile("config"))
+	}
+
+	if err := json.Unmarshal(data, conf); err != nil {
+		return nil, fmt.Errorf("could not parse configuration file at '%s': %s", configFile("config"), err)
+	}
+
+	config = conf
+
+	return conf, nil
+}
+
+func readPIN(meta *cli.Metadata) (string, error) {
+	policy := meta.GetPolicy()
+
+	if policy.PINEnv != "" {
+		if pin := os.Getenv(policy.PINEnv); pin != "" {
+			return pin, nil
+		}
+	}
+
+	if policy.PINCommand != "" {
+		if meta.DryRun {
+			return "", nil
+		}
+
+		cmd := exec.Command("sh", "-c", policy.PINCommand)
+		cmd.Stderr = os.Stderr
+
+		out, err := cmd.Output()
+		if err != nil {
+			return "", fmt.Errorf("could not get PIN from '%s'", policy.PINCommand)
+		}
+
+		return strings.TrimSpace(string(out)), nil
+	}
+
+	if meta.AutoConfirm {
+		return "", fmt.Errorf("no PIN source is configured for non-interactive use")
+	}
+
+	if meta.DryRun {
+		return "", nil
+	}
+
+	return cli.ReadSecret("Enter your PIN:")
+}
@@ -0,0 +1,72 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/apognu/n26/cli"
+)
+
+func TestReadPINDryRun(t *testing.T) {
+	t.Setenv("N26_TEST_PIN", "")
+
+	tests := []struct {
+		name   string
+		policy cli.Policy
+		auto   bool
+		err    bool
+	}{
+		{"interactive", cli.Policy{}, false, false},
+		{"unattended without PIN source", cli.Policy{}, true, true},
+		{"unattended with an unset variable", cli.Policy{PINEnv: "N26_TEST_PIN"}, true, true},
+		{"unattended with a PIN command", cli.Policy{PINCommand: "exit 1"}, true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			meta := &cli.Metadata{Config: &cli.Config{Policy: tt.policy}, DryRun: true, AutoConfirm: tt.auto}
+
+			if _, err := readPIN(meta); (err != nil) != tt.err {
+				t.Errorf("readPIN() = %v, want error: %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestReadPIN(t *testing.T) {
+	t.Setenv("N26_TEST_PIN", "1234")
+	t.Setenv("N26_TEST_EMPTY_PIN", "")
+
+	tests := []struct {
+		name   string
+		policy cli.Policy
+		pin    string
+		err    bool
+	}{
+		{name: "variable", policy: cli.Policy{PINEnv: "N26_TEST_PIN"}, pin: "1234"},
+		{name: "variable before command", policy: cli.Policy{PINEnv: "N26_TEST_PIN", PINCommand: "echo 5678"}, pin: "1234"},
+		{name: "unset variable", policy: cli.Policy{PINEnv: "N26_TEST_EMPTY_PIN"}, err: true},
+		{name: "unset variable with a command", policy: cli.Policy{PINEnv: "N26_TEST_EMPTY_PIN", PINCommand: "echo 5678"}, pin: "5678"},
+		{name: "command", policy: cli.Policy{PINCommand: "printf ' 5678\\n'"}, pin: "5678"},
+		{name: "failing command", policy: cli.Policy{PINCommand: "exit 1"}, err: true},
+		{name: "no PIN source", err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			meta := &cli.Metadata{Config: &cli.Config{Policy: tt.policy}, AutoConfirm: true}
+
+			pin, err := readPIN(meta)
+
+			if tt.err {
+				if err == nil {
+					t.Errorf("readPIN() = %q, want an error", pin)
+				}
+				return
+			}
+
+			if err != nil || pin != tt.pin {
+				t.Errorf("readPIN() = (%q, %v), want %q", pin, err, tt.pin)
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package api
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+
+	"github.com/apognu/n26/cli"
+)
+
+// CredentialsProvider returns the email address and password used to log
+// in to N26.
+type CredentialsProvider func() (username string, password string, err error)
+
+var credentialsProvider = EnvCredentials("")
+
+// SetCredentialsProvider replaces the way credentials are obtained when
+// logging in, which defaults to EnvCredentials.
+func SetCredentialsProvider(provider CredentialsProvider) {
+	credentialsProvider = provider
+}
+
+// EnvCredentials reads the credentials from N26_USERNAME and N26_PASSWORD,
+// and asks for those which are not set.
+func EnvCredentials(username string) CredentialsProvider {
+	return func() (string, string, error) {
+		if password := os.Getenv("N26_PASSWORD"); password != "" {
+			return readUsername(username), password, nil
+		}
+
+		username := readUsername(username)
+
+		password, err := cli.ReadSecret("N26 password:")
+		if err != nil {
+			return "", "", fmt.Errorf("could not read password")
+		}
+
+		return username, password, nil
+	}
+}
+
+// CommandCredentials reads the password from the first line of the output
+// of a command, such as a password manager.
+func CommandCredentials(username, command string) CredentialsProvider {
+	return func() (string, string, error) {
+		cmd := exec.Command("sh", "-c", command)
+		cmd.Stderr = os.Stderr
+
+		out, err := cmd.Output()
+		if err != nil {
+			return "", "", fmt.Errorf("could not get password from '%s'", command)
+		}
+
+		return readUsername(username), strings.SplitN(string(out), "\n", 2)[0], nil
+	}
+}
+
+// FileDescriptorCredentials reads the password from the first line read
+// from a file descriptor. Since it can only be read once, the credentials
+// are kept in memory for any later login of the same process.
+func FileDescriptorCredentials(username string, fd int) CredentialsProvider {
+	var (
+		once     sync.Once
+		password string
+		err      error
+	)
+
+	return func() (string, string, error) {
+		once.Do(func() {
+			if password, err = readLine(fd); err != nil {
+				err = fmt.Errorf("could not read password from file descriptor %d", fd)
+				return
+			}
+
+			username = readUsername(username)
+		})
+
+		return username, password, err
+	}
+}
+
+func readUsername(username string) string {
+	if username != "" {
+		return username
+	}
+	if username := os.Getenv("N26_USERNAME"); username != "" {
+		return username
+	}
+	return cli.ReadLine("N26 email address:")
+}
+
+func readLine(fd int) (string, error) {
+	line, err := bufio.NewReader(os.NewFile(uintptr(fd), fmt.Sprintf("fd%d", fd))).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+
+	return strings.TrimRight(line, "\r\n"), nil
+}
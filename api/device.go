@@ -0,0 +1,85 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/apognu/n26/cli"
+	"golang.org/x/oauth2"
+)
+
+var (
+	deviceToken   string
+	deviceTokenMu sync.Mutex
+)
+
+// DeviceToken returns the identifier this installation registers with, the
+// same way the mobile app does, so its sessions do not replace the app's.
+func DeviceToken() string {
+	deviceTokenMu.Lock()
+	defer deviceTokenMu.Unlock()
+
+	if deviceToken != "" {
+		return deviceToken
+	}
+
+	if data, err := ioutil.ReadFile(globalFile("device")); err == nil {
+		deviceToken = strings.TrimSpace(string(data))
+		return deviceToken
+	}
+
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		cli.Fatal(fmt.Errorf("could not generate device token"))
+	}
+
+	// Random UUID (version 4, RFC 4122 variant)
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+
+	token := fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+
+	// The token is published with a link, which fails if another process
+	// published its own in the meantime, in which case that one is used.
+	if err := linkFile(globalFile("device"), []byte(token)); os.IsExist(err) {
+		data, err := ioutil.ReadFile(globalFile("device"))
+		if err != nil {
+			cli.Fatal(fmt.Errorf("could not read device token from '%s'", globalFile("device")))
+		}
+
+		token = strings.TrimSpace(string(data))
+	} else if err != nil {
+		cli.Fatal(fmt.Errorf("could not write device token to '%s'", globalFile("device")))
+	}
+
+	deviceToken = token
+
+	return deviceToken
+}
+
+type deviceTransport struct {
+	base http.RoundTripper
+}
+
+func (t deviceTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("device-token", DeviceToken())
+
+	return t.base.RoundTrip(req)
+}
+
+func httpClient() *http.Client {
+	return &http.Client{Transport: deviceTransport{base: http.DefaultTransport}}
+}
+
+// authContext makes the OAuth library send the device token with token
+// requests as well as API requests.
+func authContext() context.Context {
+	return context.WithValue(context.Background(), oauth2.HTTPClient, httpClient())
+}
@@ -0,0 +1,134 @@
+package api
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/apognu/n26/cli"
+)
+
+// GetEnvelopePlan computes what each envelope defined in the configuration
+// file should receive from the income of the current month, and what must
+// be transferred from the main account to each of their spaces.
+func (cl *N26Client) GetEnvelopePlan(meta *cli.Metadata) (*cli.EnvelopePlan, error) {
+	envelopes := meta.GetEnvelopes()
+	if len(envelopes) == 0 {
+		return nil, fmt.Errorf("no envelope is defined in the configuration file")
+	}
+
+	now := time.Now().In(cli.Location)
+	period, err := parsePeriod("this-month", "this-month", now)
+	if err != nil {
+		return nil, err
+	}
+
+	stats, err := cl.getStatistics(period)
+	if err != nil {
+		return nil, err
+	}
+
+	spaces, err := cl.GetSpaces(meta)
+	if err != nil {
+		return nil, fmt.Errorf("could not get your spaces")
+	}
+
+	plan := &cli.EnvelopePlan{Month: period.From, Income: stats.TotalIncome}
+
+	for _, space := range spaces.Spaces {
+		if space.Primary {
+			plan.MainSpaceID = space.ID
+			plan.Available = space.Balance.AvailableBalance
+			plan.Currency = space.Balance.Currency
+		}
+	}
+
+	if plan.MainSpaceID == "" {
+		return nil, fmt.Errorf("could not find your main account")
+	}
+
+	spent := make(map[string]cli.Amount)
+	for _, m := range stats.Movements {
+		spent[m.Category] += m.Expense
+	}
+
+	budgets := meta.GetBudgets()
+
+	for _, conf := range envelopes {
+		space := getSpaceFromID(spaces, conf.Space)
+		if space == nil {
+			return nil, fmt.Errorf("could not find the space '%s' of an envelope", conf.Space)
+		}
+		if space.Primary {
+			return nil, fmt.Errorf("the main account cannot be used as an envelope")
+		}
+
+		envelope := cli.Envelope{SpaceID: space.ID, Space: space.Name, Balance: space.Balance.AvailableBalance}
+
+		for _, category := range conf.Categories {
+			if _, ok := meta.Categories[category]; !ok {
+				return nil, fmt.Errorf("unknown category '%s' in the envelope '%s'", category, space.Name)
+			}
+
+			envelope.Spent += spent[category]
+		}
+
+		switch {
+		case conf.Amount > 0:
+			envelope.Target = conf.Amount
+		case conf.Percent > 0:
+			envelope.Target = plan.Income.Mul(conf.Percent / 100).Round(plan.Currency)
+		default:
+			for _, category := range conf.Categories {
+				envelope.Target += budgets[category]
+			}
+		}
+
+		plan.Envelopes = append(plan.Envelopes, envelope)
+	}
+
+	return plan, nil
+}
+
+// FundEnvelopes performs the space transfers proposed by the envelope plan,
+// each of them being confirmed, limited and audited like any other space
+// transfer.
+func (cl *N26Client) FundEnvelopes(meta *cli.Metadata) (cli.Printable, error) {
+	plan, err := cl.GetEnvelopePlan(meta)
+	if err != nil {
+		return nil, err
+	}
+
+	if plan.Transfers() == 0 {
+		return (cli.SimpleMessage)("Your envelopes are already funded."), nil
+	}
+
+	if plan.Transfers() > plan.Available {
+		return nil, fmt.Errorf("funding your envelopes requires %s but your main account only holds %s", cli.Curr(plan.Transfers(), plan.Currency), cli.Curr(plan.Available, plan.Currency))
+	}
+
+	requests := make(cli.DryRunList, 0)
+	funded := 0
+
+	for _, envelope := range plan.Envelopes {
+		if envelope.Transfer() == 0 {
+			continue
+		}
+
+		out, err := cl.CreateSpaceTransfer(meta, plan.MainSpaceID, envelope.SpaceID, envelope.Transfer())
+		if err != nil {
+			return nil, fmt.Errorf("could not fund the envelope '%s' after %d transfers: %s", envelope.Space, funded, err)
+		}
+
+		if list, ok := out.(cli.DryRunList); ok {
+			requests = append(requests, list...)
+		}
+
+		funded++
+	}
+
+	if meta.DryRun {
+		return requests, nil
+	}
+
+	return (cli.SimpleMessage)(fmt.Sprintf("Your %d envelopes have been funded with %s, %s of this month's income remain unallocated.", funded, cli.Curr(plan.Transfers(), plan.Currency), cli.Curr(plan.Unallocated(), plan.Currency))), nil
+}
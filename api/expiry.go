@@ -0,0 +1,71 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/apognu/n26/cli"
+	"golang.org/x/oauth2"
+)
+
+const (
+	// Used when N26 does not tell when an access token expires
+	assumedTokenLifetime = 50 * time.Minute
+	// Tokens are refreshed a little before they expire, so they cannot
+	// expire while a request is being sent. Every token handed to the
+	// client, whether loaded, issued or refreshed, goes through
+	// Credentials.Token to apply it
+	refreshMargin = time.Minute
+	// Users are warned when they will have to log in again within this delay
+	refreshWarning = 72 * time.Hour
+)
+
+func tokenExpiry(token *oauth2.Token) time.Time {
+	if token.Expiry.IsZero() {
+		return time.Now().Add(assumedTokenLifetime)
+	}
+	return token.Expiry
+}
+
+// refreshExpiry returns when the refresh token expires, as told by N26 when
+// it issued it, or as configured. A zero time means it is unknown.
+func refreshExpiry(token *oauth2.Token) time.Time {
+	if seconds, ok := extraSeconds(token, "refresh_token_expires_in"); ok {
+		return time.Now().Add(time.Duration(seconds) * time.Second)
+	}
+
+	if previous := new(Credentials); json.Unmarshal(getSavedCredentials(), previous) == nil && previous.RefreshToken == token.RefreshToken {
+		return previous.RefreshExpiry
+	}
+
+	if lifetime, err := time.ParseDuration(config.Credentials.RefreshTokenLifetime); err == nil {
+		return time.Now().Add(lifetime)
+	}
+
+	return time.Time{}
+}
+
+func extraSeconds(token *oauth2.Token, key string) (int64, bool) {
+	switch value := token.Extra(key).(type) {
+	case float64:
+		return int64(value), value > 0
+	case int64:
+		return value, value > 0
+	case string:
+		seconds, err := strconv.ParseInt(value, 10, 64)
+		return seconds, err == nil && seconds > 0
+	}
+	return 0, false
+}
+
+func warnExpiry(creds *Credentials) {
+	if creds.RefreshExpiry.IsZero() {
+		return
+	}
+
+	if time.Until(creds.RefreshExpiry) < refreshWarning {
+		cli.Warn(fmt.Errorf("your session expires on %s, run 'n26 auth login' to renew it", creds.RefreshExpiry.In(cli.Location).Format("02 Jan 2006 15:04")))
+	}
+}
@@ -0,0 +1,160 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/apognu/n26/cli"
+)
+
+// forecastHistory is the number of months of transactions recurring
+// movements are detected in.
+const forecastHistory = 12
+
+// standingOrderPeriods maps the frequencies of standing orders to the number
+// of months between two executions, weekly ones being handled apart.
+var standingOrderPeriods = map[string]int{
+	"MONTHLY":     1,
+	"QUARTERLY":   3,
+	"HALF_YEARLY": 6,
+	"YEARLY":      12,
+	"ANNUALLY":    12,
+}
+
+func (cl *N26Client) getStandingOrders() ([]cli.StandingOrder, error) {
+	req := &N26Request{
+		Method:  http.MethodGet,
+		Path:    "/api/transactions/so",
+		Decoder: NewJSON(new(cli.StandingOrders)),
+	}
+
+	output, err := cl.Request(req, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if orders, ok := output.(*cli.StandingOrders); ok {
+		return orders.Data, nil
+	}
+
+	return nil, fmt.Errorf("could not unmarshal upstream data")
+}
+
+// GetForecast projects the usable balance for each of the next days, from
+// the standing orders of the account and the recurring movements detected
+// in its transactions.
+func (cl *N26Client) GetForecast(meta *cli.Metadata, days int) (*cli.Forecast, error) {
+	if days < 1 {
+		return nil, fmt.Errorf("the forecast must cover at least one day")
+	}
+
+	balance, err := cl.GetBalance(meta)
+	if err != nil {
+		return nil, fmt.Errorf("could not get current balance")
+	}
+
+	now := time.Now().In(cli.Location)
+	period, err := parsePeriod(fmt.Sprintf("-%dm", forecastHistory), "", now)
+	if err != nil {
+		return nil, err
+	}
+
+	transactions, err := cl.getAllTransactions(period)
+	if err != nil {
+		return nil, err
+	}
+
+	orders, err := cl.getStandingOrders()
+	if err != nil {
+		cli.Warn(fmt.Errorf("could not get your standing orders, they are only forecast if detected in your transactions"))
+	}
+
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, cli.Location)
+	end := today.AddDate(0, 0, days)
+
+	movements := make(map[string][]cli.ForecastMovement)
+	add := func(t time.Time, name string, amount cli.Amount) {
+		if !t.Before(today) && t.Before(end) {
+			day := t.Format("2006-01-02")
+			movements[day] = append(movements[day], cli.ForecastMovement{Name: name, Amount: amount})
+		}
+	}
+
+	ordered := make(map[string]bool)
+
+	for _, order := range orders {
+		// Orders in another currency are forecast from the transactions
+		// they made in the currency of the account, if they are detected.
+		if order.Currency != "" && order.Currency != balance.Currency {
+			cli.Warn(fmt.Errorf("the standing order to '%s' is in %s and is not forecast", order.PartnerName, order.Currency))
+			continue
+		}
+
+		ordered[strings.ToLower(order.PartnerName)] = true
+
+		stop := end
+		if order.Stop > 0 && cli.FromMillis(order.Stop).Before(stop) {
+			stop = cli.FromMillis(order.Stop)
+		}
+
+		for t := cli.FromMillis(order.Next); !t.After(stop); {
+			add(t, order.PartnerName, -order.Amount.Abs())
+
+			if order.Frequency == "WEEKLY" {
+				t = t.AddDate(0, 0, 7)
+			} else if months, ok := standingOrderPeriods[order.Frequency]; ok {
+				t = t.AddDate(0, months, 0)
+			} else {
+				break
+			}
+		}
+	}
+
+	for _, incoming := range []bool{true, false} {
+		for _, sub := range detectRecurring(transactions, now, incoming) {
+			c, _ := getCadence(sub.Cadence)
+
+			// Movements that stopped are not expected anymore, and standing
+			// orders are already accounted for.
+			if !c.next(sub.Last).Equal(sub.Next) || ordered[strings.ToLower(sub.Name)] {
+				continue
+			}
+
+			amount := sub.Amount
+			if !incoming {
+				amount = -amount
+			}
+
+			// A movement that is late, but not enough to be considered
+			// missed, is still expected today.
+			for t := sub.Next; t.Before(end); t = c.next(t) {
+				if t.Before(today) {
+					add(today, sub.Name, amount)
+				} else {
+					add(t, sub.Name, amount)
+				}
+			}
+		}
+	}
+
+	forecast := &cli.Forecast{
+		Currency:  balance.Currency,
+		Balance:   balance.UsageBalance,
+		Threshold: meta.GetForecastThreshold(),
+	}
+
+	current := balance.UsageBalance
+	for day := today; day.Before(end); day = day.AddDate(0, 0, 1) {
+		d := cli.ForecastDay{Date: day, Movements: movements[day.Format("2006-01-02")]}
+		for _, m := range d.Movements {
+			current += m.Amount
+		}
+		d.Balance = current
+
+		forecast.Days = append(forecast.Days, d)
+	}
+
+	return forecast, nil
+}
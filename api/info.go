@@ -108,23 +108,71 @@ func (cl *N26Client) GetCategories() (map[string]string, error) {
 	return nil, fmt.Errorf("could not unmarshal upstream data")
 }
 
+// statsTransactionLimit bounds the number of transactions fetched to compute
+// the per-currency totals of a period.
+const statsTransactionLimit = 10000
+
+var errTooManyTransactions = fmt.Errorf("the period holds more than %d transactions, which cannot all be fetched, please use a shorter period", statsTransactionLimit)
+
+// getAllTransactions fetches every transaction of a period, and fails rather
+// than computing anything from a list cut off at the limit.
+func (cl *N26Client) getAllTransactions(period Period) (cli.PastTransactionList, error) {
+	transactions, err := cl.getTransactions(period, statsTransactionLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(transactions) >= statsTransactionLimit {
+		return nil, errTooManyTransactions
+	}
+
+	return transactions, nil
+}
+
 func (cl *N26Client) GetStatistics(meta *cli.Metadata, from, to string) (*cli.Statistics, error) {
-	now := time.Now()
-	start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.Local)
-	end := start.AddDate(0, 1, 0).Add(-time.Nanosecond)
-
-	if from != "" {
-		var serr, eerr error
-		start, serr = time.Parse("2006-01-02", from)
-		end, eerr = time.Parse("2006-01-02", to)
-		if serr != nil || eerr != nil {
-			return nil, fmt.Errorf("could not parse the provided dates")
-		}
+	period, err := ParsePeriod(from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	if meta.GetCategories() == nil {
+		return nil, fmt.Errorf("could not get categories")
+	}
+
+	stats, err := cl.getStatistics(period)
+	if err != nil {
+		return nil, err
 	}
 
+	balance, err := cl.GetBalance(meta)
+	if err != nil {
+		return nil, fmt.Errorf("could not get current balance")
+	}
+
+	stats.Categories = meta.Categories
+	stats.Currency = balance.Currency
+
+	// The movements by currency are left out of periods too long to fetch
+	// every transaction, instead of being computed from part of them.
+	transactions, err := cl.getAllTransactions(period)
+	if err == errTooManyTransactions {
+		cli.Warn(fmt.Errorf("the period holds more than %d transactions, movements by currency and FX fees are not shown", statsTransactionLimit))
+		return stats, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	stats.Transactions = transactions
+	stats.Currencies, stats.FXFees = currencyTotals(transactions)
+
+	return stats, nil
+}
+
+func (cl *N26Client) getStatistics(period Period) (*cli.Statistics, error) {
 	req := &N26Request{
 		Method:  http.MethodGet,
-		Path:    fmt.Sprintf("/api/smrt/statistics/categories/%d/%d", start.Unix()*1000, end.Unix()*1000),
+		Path:    fmt.Sprintf("/api/smrt/statistics/categories/%d/%d", cli.Millis(period.From), cli.Millis(period.To)),
 		Decoder: NewJSON(new(cli.Statistics)),
 	}
 
@@ -134,14 +182,135 @@ func (cl *N26Client) GetStatistics(meta *cli.Metadata, from, to string) (*cli.St
 	}
 
 	if stats, ok := output.(*cli.Statistics); ok {
-		if meta.GetCategories() == nil {
-			return nil, fmt.Errorf("could not get categories")
+		return stats, nil
+	}
+
+	return nil, fmt.Errorf("could not unmarshal upstream data")
+}
+
+// trendWorkers is the number of months whose statistics are fetched at the
+// same time.
+const trendWorkers = 4
+
+// GetStatisticsTrend fetches the statistics of each of the last months,
+// including the current one, and of the same month one year before the
+// current one to compare it to.
+func (cl *N26Client) GetStatisticsTrend(meta *cli.Metadata, months int) (*cli.Trend, error) {
+	if months < 1 {
+		return nil, fmt.Errorf("the trend must cover at least one month")
+	}
+
+	if meta.GetCategories() == nil {
+		return nil, fmt.Errorf("could not get categories")
+	}
+
+	now := time.Now().In(cli.Location)
+	current := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, cli.Location)
+
+	trend := &cli.Trend{Categories: meta.Categories, Months: make([]time.Time, months)}
+	stats := make([]*cli.Statistics, months+1)
+	jobs := make([]func() error, months+1)
+
+	for idx := range jobs {
+		idx := idx
+		month := current.AddDate(0, idx-months+1, 0)
+		if idx == months {
+			month = current.AddDate(-1, 0, 0)
 		}
 
-		stats.Categories = meta.Categories
+		start, end, _ := span(month, 0, 1, 0)
+		if idx < months {
+			trend.Months[idx] = start
+		}
 
-		return stats, nil
+		jobs[idx] = func() error {
+			s, err := cl.getStatistics(Period{From: start, To: end})
+			stats[idx] = s
+			return err
+		}
 	}
 
-	return nil, fmt.Errorf("could not unmarshal upstream data")
+	if err := Parallel(trendWorkers, jobs...); err != nil {
+		return nil, err
+	}
+
+	indexes := make(map[string]int)
+
+	for month, s := range stats[:months] {
+		for _, m := range s.Movements {
+			if m.Income == 0 && m.Expense == 0 {
+				continue
+			}
+
+			idx, ok := indexes[m.Category]
+			if !ok {
+				idx = len(trend.Movements)
+				indexes[m.Category] = idx
+				trend.Movements = append(trend.Movements, cli.CategoryTrend{
+					Category: m.Category,
+					Income:   make([]cli.Amount, months),
+					Expense:  make([]cli.Amount, months),
+				})
+			}
+
+			trend.Movements[idx].Income[month] += m.Income
+			trend.Movements[idx].Expense[month] += m.Expense
+		}
+	}
+
+	// Categories with no movement over the displayed months have no row to
+	// compare to the previous year.
+	for _, m := range stats[months].Movements {
+		if idx, ok := indexes[m.Category]; ok {
+			trend.Movements[idx].YearAgoIncome += m.Income
+			trend.Movements[idx].YearAgoExpense += m.Expense
+		}
+	}
+
+	return trend, nil
+}
+
+// currencyTotals sums the transactions by the currency they were made in,
+// leaving out transfers between spaces. FX fees are converted at the same
+// rate as the transactions when they are reported in another currency.
+func currencyTotals(transactions cli.PastTransactionList) ([]cli.CurrencyTotal, cli.Amount) {
+	totals := make([]cli.CurrencyTotal, 0)
+	indexes := make(map[string]int)
+	fees := cli.Amount(0)
+
+	for _, trx := range transactions {
+		if trx.Scheme == "SPACES" {
+			continue
+		}
+
+		currency, amount := trx.Currency, trx.Amount
+		if trx.Foreign() {
+			currency, amount = trx.OriginalCurrency, trx.OriginalAmount
+		}
+
+		idx, ok := indexes[currency]
+		if !ok {
+			idx = len(totals)
+			indexes[currency] = idx
+			totals = append(totals, cli.CurrencyTotal{Currency: currency})
+		}
+
+		fee := trx.FXFee()
+		if trx.Report != nil {
+			fee = fee.Exchange(trx.Amount.Float(), trx.Report.Amount.Float()).Round(trx.Report.Currency)
+		}
+
+		total := &totals[idx]
+		total.Transactions++
+		total.FXFees += fee
+		fees += fee
+
+		if trx.Amount < 0 {
+			total.Expense += amount.Abs()
+		} else {
+			total.Income += amount.Abs()
+		}
+	}
+
+	return totals, fees
 }
@@ -0,0 +1,132 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/apognu/n26/cli"
+	"golang.org/x/oauth2"
+)
+
+// testClient returns a client sending requests to the fake server with a
+// valid token.
+func testClient() *N26Client {
+	return &N26Client{
+		http:   httpClient(),
+		config: oauthConfig(),
+		source: oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "access", Expiry: time.Now().Add(time.Hour)}),
+	}
+}
+
+func TestStatisticsTrendYearOverYear(t *testing.T) {
+	now := time.Now().In(cli.Location)
+	current := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, cli.Location)
+	yearAgo := current.AddDate(-1, 0, 0)
+
+	fakeN26(t, func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.Split(r.URL.Path, "/")
+		from, _ := strconv.ParseInt(parts[len(parts)-2], 10, 64)
+
+		switch start := time.Unix(0, from*int64(time.Millisecond)); {
+		case start.Equal(current):
+			reply(w, 200, `{"items":[{"id":"groceries","expense":150,"income":0}]}`)
+		case start.Equal(yearAgo):
+			reply(w, 200, `{"items":[{"id":"groceries","expense":100,"income":0},{"id":"travel","expense":80,"income":0}]}`)
+		default:
+			reply(w, 200, `{"items":[]}`)
+		}
+	})
+
+	cl := testClient()
+
+	var want cli.Amount
+	if err := want.UnmarshalJSON([]byte("100")); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, months := range []int{1, 12, 13} {
+		t.Run(fmt.Sprintf("%d months", months), func(t *testing.T) {
+			trend, err := cl.GetStatisticsTrend(&cli.Metadata{Categories: map[string]string{}}, months)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(trend.Months) != months || !trend.Months[months-1].Equal(current) {
+				t.Fatalf("trend covers %v, want %d months up to %s", trend.Months, months, current)
+			}
+
+			var groceries *cli.CategoryTrend
+			for idx := range trend.Movements {
+				if trend.Movements[idx].Category == "groceries" {
+					groceries = &trend.Movements[idx]
+				}
+			}
+
+			if groceries == nil {
+				t.Fatalf("trend has movements %+v, want groceries", trend.Movements)
+			}
+
+			if groceries.YearAgoExpense != want {
+				t.Errorf("groceries a year ago = %s, want %s", groceries.YearAgoExpense, want)
+			}
+		})
+	}
+}
+
+func TestGetAllTransactionsLimit(t *testing.T) {
+	for _, count := range []int{statsTransactionLimit - 1, statsTransactionLimit} {
+		t.Run(fmt.Sprintf("%d transactions", count), func(t *testing.T) {
+			fakeN26(t, func(w http.ResponseWriter, r *http.Request) {
+				reply(w, 200, "["+strings.TrimSuffix(strings.Repeat(`{"amount":-1},`, count), ",")+"]")
+			})
+
+			cl := testClient()
+
+			transactions, err := cl.getAllTransactions(Period{From: time.Unix(0, 0), To: time.Now()})
+			if count < statsTransactionLimit && (err != nil || len(transactions) != count) {
+				t.Errorf("getAllTransactions() = (%d transactions, %v), want %d", len(transactions), err, count)
+			}
+			if count == statsTransactionLimit && err == nil {
+				t.Error("getAllTransactions() returned transactions cut off at the limit")
+			}
+		})
+	}
+}
+
+func TestGetStatisticsCurrencies(t *testing.T) {
+	for _, count := range []int{0, 1, statsTransactionLimit} {
+		t.Run(fmt.Sprintf("%d transactions", count), func(t *testing.T) {
+			fakeN26(t, func(w http.ResponseWriter, r *http.Request) {
+				switch {
+				case r.URL.Path == "/api/accounts":
+					reply(w, 200, `{"availableBalance":100,"usableBalance":100,"currency":"EUR"}`)
+				case r.URL.Path == "/api/smrt/transactions":
+					reply(w, 200, "["+strings.TrimSuffix(strings.Repeat(`{"amount":-1,"currencyCode":"USD"},`, count), ",")+"]")
+				default:
+					reply(w, 200, `{"items":[]}`)
+				}
+			})
+
+			stats, err := testClient().GetStatistics(&cli.Metadata{Categories: map[string]string{}}, "2018-01-01", "2018-01-31")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if stats.Currency != "EUR" {
+				t.Errorf("statistics are in %q, want the account currency EUR", stats.Currency)
+			}
+
+			want := 1
+			if count == 0 || count == statsTransactionLimit {
+				want = 0
+			}
+			if len(stats.Currencies) != want {
+				t.Errorf("statistics hold movements in %d currencies, want %d", len(stats.Currencies), want)
+			}
+		})
+	}
+}
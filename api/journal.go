@@ -0,0 +1,198 @@
+package api
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/apognu/n26/cli"
+)
+
+const (
+	journalPending = "pending"
+	journalDone    = "done"
+	journalFailed  = "failed"
+)
+
+var batchIDRegexp = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
+type journalEntry struct {
+	state string
+	key   string
+}
+
+type journal struct {
+	path    string
+	restart bool
+	entries map[int]journalEntry
+}
+
+// batchID returns the provided ID of a batch or, by default, one derived from
+// the path of the batch file.
+func batchID(file, id string) (string, error) {
+	if id == "" {
+		path, err := filepath.Abs(file)
+		if err != nil {
+			return "", fmt.Errorf("could not resolve batch file '%s'", file)
+		}
+
+		sum := sha256.Sum256([]byte(path))
+		id = fmt.Sprintf("%x", sum[:8])
+	}
+
+	if !batchIDRegexp.MatchString(id) {
+		return "", fmt.Errorf("the batch ID can only contain letters, digits, dots, dashes and underscores")
+	}
+
+	return id, nil
+}
+
+// journalPath returns the location of the journal of a batch, named after
+// its ID.
+func journalPath(file, id string) (string, error) {
+	id, err := batchID(file, id)
+	if err != nil {
+		return "", err
+	}
+
+	return configFile(fmt.Sprintf("batch-%s.journal", id)), nil
+}
+
+// transferKey identifies a row of a batch, so that a row changed since it was
+// recorded is not mistaken for the transfer that was performed.
+func transferKey(idx int, trx cli.BatchTransfer) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%d\x00%s\x00%s\x00%s", idx, trx.Recipient, trx.Amount, trx.Reference)))
+
+	return fmt.Sprintf("%x", sum[:8])
+}
+
+// openJournal reads the progress of a batch. When restarting, the previous
+// progress is discarded on the first recorded transfer, unless a transfer was
+// left pending.
+func openJournal(path string, restart bool) (*journal, error) {
+	j := &journal{
+		path:    path,
+		restart: restart,
+		entries: make(map[int]journalEntry),
+	}
+
+	file, err := os.Open(j.path)
+	if os.IsNotExist(err) {
+		return j, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not read batch journal at '%s'", j.path)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) < 2 || len(fields) > 3 {
+			return nil, fmt.Errorf("could not parse batch journal at '%s'", j.path)
+		}
+
+		idx, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("could not parse batch journal at '%s'", j.path)
+		}
+
+		entry := journalEntry{state: fields[0]}
+		if len(fields) == 3 {
+			entry.key = fields[2]
+		}
+
+		j.entries[idx] = entry
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("could not read batch journal at '%s'", j.path)
+	}
+
+	if restart {
+		for idx, entry := range j.entries {
+			if entry.state == journalPending {
+				return nil, fmt.Errorf("transfer #%d of the previous run of this batch was interrupted and may have been performed, check your transactions and replace 'pending' with 'done' or 'failed' on its line in '%s' before restarting", idx+1, j.path)
+			}
+		}
+
+		j.entries = make(map[int]journalEntry)
+	}
+
+	return j, nil
+}
+
+// Remaining returns the rows of a batch that still have to be performed.
+func (j *journal) Remaining(batch cli.BatchTransferList) ([]int, error) {
+	indexes := make([]int, 0, len(batch))
+
+	for idx, trx := range batch {
+		entry, ok := j.entries[idx]
+		if !ok {
+			indexes = append(indexes, idx)
+			continue
+		}
+
+		if entry.key != "" && entry.key != transferKey(idx, trx) {
+			// A failed row that was fixed is a new transfer, anything else
+			// may already have been paid with the previous details.
+			if entry.state == journalFailed {
+				indexes = append(indexes, idx)
+				continue
+			}
+
+			return nil, fmt.Errorf("transfer #%d was changed after it was sent, restore it or start a new batch with --id or --restart", idx+1)
+		}
+
+		switch entry.state {
+		case journalDone:
+			continue
+		case journalPending:
+			return nil, fmt.Errorf("transfer #%d of this batch was interrupted and may have been performed, check your transactions and replace 'pending' with 'done' or 'failed' on its line in '%s'", idx+1, j.path)
+		}
+
+		indexes = append(indexes, idx)
+	}
+
+	if len(indexes) == 0 {
+		return nil, fmt.Errorf("every transfer of this batch has already been performed, use --restart or a new --id to perform it again")
+	}
+
+	return indexes, nil
+}
+
+func (j *journal) Record(idx int, trx cli.BatchTransfer, state string) error {
+	flags := os.O_WRONLY | os.O_APPEND | os.O_CREATE
+	if j.restart {
+		flags |= os.O_TRUNC
+	}
+
+	file, err := os.OpenFile(j.path, flags, 0600)
+	if err != nil {
+		return fmt.Errorf("could not write batch journal at '%s'", j.path)
+	}
+	defer file.Close()
+
+	j.restart = false
+
+	key := transferKey(idx, trx)
+
+	if _, err := fmt.Fprintf(file, "%s %d %s\n", state, idx, key); err != nil {
+		return fmt.Errorf("could not write batch journal at '%s'", j.path)
+	}
+	if err := file.Sync(); err != nil {
+		return fmt.Errorf("could not write batch journal at '%s'", j.path)
+	}
+
+	j.entries[idx] = journalEntry{state: state, key: key}
+
+	return nil
+}
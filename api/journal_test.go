@@ -0,0 +1,242 @@
+package api
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/apognu/n26/cli"
+)
+
+func payroll(amounts ...cli.Amount) cli.BatchTransferList {
+	batch := make(cli.BatchTransferList, len(amounts))
+	for idx, amount := range amounts {
+		batch[idx] = cli.BatchTransfer{Recipient: fmt.Sprintf("employee%d@example.com", idx), Amount: amount, Reference: "Salary"}
+	}
+	return batch
+}
+
+func TestJournal(t *testing.T) {
+	type record struct {
+		idx   int
+		state string
+	}
+
+	tests := []struct {
+		name      string
+		performed cli.BatchTransferList
+		records   []record
+		batch     cli.BatchTransferList
+		restart   bool
+		remaining []int
+		err       bool
+	}{
+		{
+			name:      "new batch",
+			batch:     payroll(100000, 200000, 300000),
+			remaining: []int{0, 1, 2},
+		},
+		{
+			name:      "resume after an interruption",
+			performed: payroll(100000, 200000, 300000),
+			records:   []record{{0, journalPending}, {0, journalDone}, {1, journalPending}, {1, journalDone}},
+			batch:     payroll(100000, 200000, 300000),
+			remaining: []int{2},
+		},
+		{
+			name:      "retry a failed transfer",
+			performed: payroll(100000, 200000, 300000),
+			records:   []record{{0, journalDone}, {1, journalFailed}},
+			batch:     payroll(100000, 200000, 300000),
+			remaining: []int{1, 2},
+		},
+		{
+			name:      "resume after fixing a failed transfer",
+			performed: payroll(100000, 200000, 300000),
+			records:   []record{{0, journalDone}, {1, journalFailed}},
+			batch:     payroll(100000, 250000, 300000),
+			remaining: []int{1, 2},
+		},
+		{
+			name:      "resume after changing a performed transfer",
+			performed: payroll(100000, 200000, 300000),
+			records:   []record{{0, journalDone}, {1, journalFailed}},
+			batch:     payroll(150000, 250000, 300000),
+			err:       true,
+		},
+		{
+			name:      "resume after inserting a row",
+			performed: payroll(100000, 200000),
+			records:   []record{{0, journalDone}, {1, journalDone}},
+			batch:     append(payroll(50000), payroll(100000, 200000)...),
+			err:       true,
+		},
+		{
+			name:      "interrupted transfer",
+			performed: payroll(100000, 200000, 300000),
+			records:   []record{{0, journalDone}, {1, journalPending}},
+			batch:     payroll(100000, 200000, 300000),
+			err:       true,
+		},
+		{
+			name:      "rerun a performed batch",
+			performed: payroll(100000, 200000),
+			records:   []record{{0, journalDone}, {1, journalDone}},
+			batch:     payroll(100000, 200000),
+			err:       true,
+		},
+		{
+			name:      "restart a performed batch",
+			performed: payroll(100000, 200000),
+			records:   []record{{0, journalDone}, {1, journalDone}},
+			batch:     payroll(100000, 200000),
+			restart:   true,
+			remaining: []int{0, 1},
+		},
+		{
+			name:      "restart an interrupted batch",
+			performed: payroll(100000, 200000),
+			records:   []record{{0, journalDone}, {1, journalPending}},
+			batch:     payroll(100000, 200000),
+			restart:   true,
+			err:       true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "batch.journal")
+
+			previous, err := openJournal(path, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, r := range tt.records {
+				if err := previous.Record(r.idx, tt.performed[r.idx], r.state); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			j, err := openJournal(path, tt.restart)
+			if err == nil {
+				var remaining []int
+				remaining, err = j.Remaining(tt.batch)
+
+				if err == nil && !reflect.DeepEqual(remaining, tt.remaining) {
+					t.Errorf("Remaining() = %v, want %v", remaining, tt.remaining)
+				}
+			}
+
+			if (err != nil) != tt.err {
+				t.Errorf("Remaining() error = %v, want an error: %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestJournalRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "batch.journal")
+	batch := payroll(100000, 200000)
+
+	j, err := openJournal(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for idx := range batch {
+		if err := j.Record(idx, batch[idx], journalDone); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A restart that records nothing, such as a dry run, keeps the progress.
+	if _, err := openJournal(path, true); err != nil {
+		t.Fatal(err)
+	}
+
+	j, err = openJournal(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := j.Remaining(batch); err == nil {
+		t.Fatal("a performed batch was resumed")
+	}
+
+	j, err = openJournal(path, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := j.Record(0, batch[0], journalDone); err != nil {
+		t.Fatal(err)
+	}
+
+	j, err = openJournal(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if remaining, err := j.Remaining(batch); err != nil || !reflect.DeepEqual(remaining, []int{1}) {
+		t.Errorf("Remaining() after a restart = (%v, %v), want [1]", remaining, err)
+	}
+}
+
+func TestJournalPath(t *testing.T) {
+	path, err := journalPath("payroll.csv", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if again, _ := journalPath("./payroll.csv", ""); again != path {
+		t.Errorf("journalPath() = %q for the same file, want %q", again, path)
+	}
+	if other, _ := journalPath("invoices.csv", ""); other == path {
+		t.Errorf("journalPath() = %q for two files", other)
+	}
+	if named, _ := journalPath("payroll.csv", "payroll-2018-11"); !strings.HasSuffix(named, "batch-payroll-2018-11.journal") {
+		t.Errorf("journalPath() = %q for a named batch", named)
+	}
+	if _, err := journalPath("payroll.csv", "../payroll"); err == nil {
+		t.Error("journalPath() accepted a batch ID outside of the configuration directory")
+	}
+}
+
+func TestLockBatch(t *testing.T) {
+	auditHome(t)
+
+	unlock, err := lockBatch("payroll")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	locked := make(chan func())
+	go func() {
+		other, err := lockBatch("payroll")
+		if err != nil {
+			t.Error(err)
+			other = func() {}
+		}
+		locked <- other
+	}()
+
+	select {
+	case <-locked:
+		t.Fatal("the same batch was locked twice")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	if other, err := lockBatch("invoices"); err != nil {
+		t.Fatal(err)
+	} else {
+		other()
+	}
+
+	unlock()
+
+	select {
+	case other := <-locked:
+		other()
+	case <-time.After(5 * time.Second):
+		t.Fatal("the batch was not unlocked")
+	}
+}
@@ -0,0 +1,95 @@
+package api
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/apognu/n26/cli"
+)
+
+func checkLimits(meta *cli.Metadata, transfers cli.BatchTransferList, outgoing bool, currency string) error {
+	limits := meta.GetLimits()
+	now := time.Now()
+
+	for _, hours := range limits.BlockedHours {
+		if hours.Contains(now.Hour()) {
+			return fmt.Errorf("money movements are blocked between %02d:00 and %02d:00", hours.From, hours.To)
+		}
+	}
+
+	for _, trx := range transfers {
+		if limits.MaxTransfer > 0 && trx.Amount > limits.MaxTransfer {
+			return fmt.Errorf("the transfer of %s to '%s' exceeds the limit of %s per transfer", cli.Curr(trx.Amount, currency), trx.Recipient, cli.Curr(limits.MaxTransfer, currency))
+		}
+
+		if outgoing && len(limits.AllowedRecipients) > 0 && !recipientAllowed(limits.AllowedRecipients, trx.Recipient) {
+			return fmt.Errorf("the recipient '%s' is not in the list of allowed recipients", trx.Recipient)
+		}
+	}
+
+	if outgoing && limits.MaxDaily > 0 {
+		spent, err := spentSince(now.Add(-24*time.Hour), currency)
+		if err != nil {
+			return err
+		}
+
+		if spent.Amount+transfers.Total() > limits.MaxDaily {
+			return fmt.Errorf("these transfers would exceed the daily limit of %s, %s were already sent in the last 24 hours", cli.Curr(limits.MaxDaily, currency), spent)
+		}
+	}
+
+	return nil
+}
+
+func confirmAmounts(meta *cli.Metadata, transfers cli.BatchTransferList, currency string) {
+	threshold := meta.GetLimits().ConfirmAmountAbove
+	if threshold <= 0 {
+		return
+	}
+
+	for _, trx := range transfers {
+		if trx.Amount > threshold {
+			cli.ConfirmAmount(meta, trx.Amount, currency)
+		}
+	}
+}
+
+func recipientAllowed(allowed []string, recipient string) bool {
+	for _, r := range allowed {
+		if normalizeIBAN(r) == normalizeIBAN(recipient) {
+			return true
+		}
+	}
+	return false
+}
+
+// spentSince sums the transfers sent since the given time, in the currency
+// of the limits. Only client errors guarantee a transfer was not performed,
+// anything else is counted since it may have been.
+func spentSince(since time.Time, currency string) (cli.Money, error) {
+	spent := cli.Money{Currency: currency}
+
+	log, err := GetAuditLog()
+	if err != nil {
+		return spent, err
+	}
+
+	for _, entry := range log {
+		if entry.Action == auditSpaceTransfer || entry.Timestamp.Before(since) || (entry.Status >= 400 && entry.Status < 500) {
+			continue
+		}
+
+		// Entries recorded before the currency was logged were made from
+		// the same account as the limits.
+		amount := cli.Money{Amount: entry.Amount, Currency: entry.Currency}
+		if amount.Currency == "" {
+			amount.Currency = currency
+		}
+
+		if spent, err = spent.Add(amount); err != nil {
+			return spent, fmt.Errorf("could not count the transfers of the last 24 hours against the daily limit: %s", err)
+		}
+	}
+
+	return spent, nil
+}
@@ -0,0 +1,170 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/apognu/n26/cli"
+)
+
+// audited records a transfer in the audit log of the test.
+func audited(t *testing.T, action string, amount cli.Amount, currency string, status int, age time.Duration) {
+	entry := auditEntry(amount)
+	entry.Action, entry.Currency, entry.Status = action, currency, status
+	entry.Timestamp = time.Now().Add(-age).UTC()
+
+	if err := appendAudit(entry); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSpentSinceCurrency(t *testing.T) {
+	tests := []struct {
+		name     string
+		currency string
+		spent    cli.Amount
+		err      bool
+	}{
+		{name: "same currency", currency: "EUR", spent: 300000},
+		{name: "recorded without currency", currency: "", spent: 300000},
+		{name: "other currency", currency: "USD", err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auditHome(t)
+
+			audited(t, auditMoneyBeam, 100000, "EUR", 200, time.Hour)
+			audited(t, auditBatchTransfer, 200000, tt.currency, 200, time.Hour)
+
+			spent, err := spentSince(time.Now().Add(-24*time.Hour), "EUR")
+
+			if tt.err {
+				if err == nil {
+					t.Errorf("spentSince() = %s, want an error", spent)
+				}
+				return
+			}
+
+			if err != nil || spent != (cli.Money{Amount: tt.spent, Currency: "EUR"}) {
+				t.Errorf("spentSince() = (%s, %v), want %s", spent, err, cli.Curr(tt.spent, "EUR"))
+			}
+		})
+	}
+}
+
+func TestHourRange(t *testing.T) {
+	tests := []struct {
+		hours   cli.HourRange
+		hour    int
+		blocked bool
+	}{
+		{cli.HourRange{From: 9, To: 17}, 9, true},
+		{cli.HourRange{From: 9, To: 17}, 16, true},
+		{cli.HourRange{From: 9, To: 17}, 17, false},
+		{cli.HourRange{From: 9, To: 17}, 8, false},
+		{cli.HourRange{From: 22, To: 6}, 22, true},
+		{cli.HourRange{From: 22, To: 6}, 23, true},
+		{cli.HourRange{From: 22, To: 6}, 0, true},
+		{cli.HourRange{From: 22, To: 6}, 5, true},
+		{cli.HourRange{From: 22, To: 6}, 6, false},
+		{cli.HourRange{From: 22, To: 6}, 12, false},
+		{cli.HourRange{From: 0, To: 0}, 0, false},
+	}
+
+	for _, tt := range tests {
+		if blocked := tt.hours.Contains(tt.hour); blocked != tt.blocked {
+			t.Errorf("%v.Contains(%d) = %v, want %v", tt.hours, tt.hour, blocked, tt.blocked)
+		}
+	}
+}
+
+func TestCheckLimits(t *testing.T) {
+	// Blocked hours are around the current hour, wrapping past midnight
+	// when needed, so that the test does not depend on when it runs.
+	hour := time.Now().Hour()
+	now := cli.HourRange{From: (hour + 23) % 24, To: (hour + 2) % 24}
+	later := cli.HourRange{From: (hour + 3) % 24, To: (hour + 5) % 24}
+
+	transfer := func(recipient string, amount cli.Amount) cli.BatchTransferList {
+		return cli.BatchTransferList{{Recipient: recipient, Amount: amount}}
+	}
+
+	tests := []struct {
+		name      string
+		limits    cli.Limits
+		transfers cli.BatchTransferList
+		outgoing  bool
+		err       bool
+	}{
+		{name: "no limits", transfers: transfer("john@example.com", 1000000), outgoing: true},
+		{name: "blocked hours", limits: cli.Limits{BlockedHours: []cli.HourRange{now}}, transfers: transfer("john@example.com", 1000), outgoing: true, err: true},
+		{name: "blocked hours between spaces", limits: cli.Limits{BlockedHours: []cli.HourRange{now}}, transfers: transfer("Savings", 1000), err: true},
+		{name: "outside of blocked hours", limits: cli.Limits{BlockedHours: []cli.HourRange{later}}, transfers: transfer("john@example.com", 1000), outgoing: true},
+		{name: "below the maximum", limits: cli.Limits{MaxTransfer: 100000}, transfers: transfer("john@example.com", 100000), outgoing: true},
+		{name: "above the maximum", limits: cli.Limits{MaxTransfer: 100000}, transfers: transfer("john@example.com", 100010), outgoing: true, err: true},
+		{name: "above the maximum between spaces", limits: cli.Limits{MaxTransfer: 100000}, transfers: transfer("Savings", 100010), err: true},
+		{name: "one of a batch above the maximum", limits: cli.Limits{MaxTransfer: 100000}, transfers: append(transfer("john@example.com", 1000), transfer("jane@example.com", 200000)...), outgoing: true, err: true},
+		{name: "allowed recipient", limits: cli.Limits{AllowedRecipients: []string{"john@example.com"}}, transfers: transfer("john@example.com", 1000), outgoing: true},
+		{name: "allowed IBAN", limits: cli.Limits{AllowedRecipients: []string{"DE89 3704 0044 0532 0130 00"}}, transfers: transfer("de89370400440532013000", 1000), outgoing: true},
+		{name: "recipient not allowed", limits: cli.Limits{AllowedRecipients: []string{"john@example.com"}}, transfers: transfer("jane@example.com", 1000), outgoing: true, err: true},
+		{name: "allow-list between spaces", limits: cli.Limits{AllowedRecipients: []string{"john@example.com"}}, transfers: transfer("Savings", 1000)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auditHome(t)
+
+			meta := &cli.Metadata{Config: &cli.Config{Limits: tt.limits}}
+
+			if err := checkLimits(meta, tt.transfers, tt.outgoing, "EUR"); (err != nil) != tt.err {
+				t.Errorf("checkLimits() = %v, want error: %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestCheckDailyLimit(t *testing.T) {
+	type entry struct {
+		action string
+		amount cli.Amount
+		status int
+		age    time.Duration
+	}
+
+	tests := []struct {
+		name    string
+		entries []entry
+		amount  cli.Amount
+		err     bool
+	}{
+		{name: "nothing sent", amount: 100000},
+		{name: "above the limit", amount: 100010, err: true},
+		{name: "performed", entries: []entry{{auditMoneyBeam, 60000, 200, time.Hour}}, amount: 40000},
+		{name: "performed above the limit", entries: []entry{{auditMoneyBeam, 60000, 200, time.Hour}}, amount: 40010, err: true},
+		{name: "batch", entries: []entry{{auditBatchTransfer, 30000, 200, time.Hour}, {auditBatchTransfer, 30000, 200, time.Hour}}, amount: 40010, err: true},
+		{name: "unknown outcome", entries: []entry{{auditMoneyBeam, 60000, 0, time.Hour}}, amount: 40010, err: true},
+		{name: "server error", entries: []entry{{auditMoneyBeam, 60000, 502, time.Hour}}, amount: 40010, err: true},
+		{name: "refused", entries: []entry{{auditMoneyBeam, 60000, 400, time.Hour}}, amount: 100000},
+		{name: "forbidden", entries: []entry{{auditMoneyBeam, 60000, 403, time.Hour}}, amount: 100000},
+		{name: "older than a day", entries: []entry{{auditMoneyBeam, 60000, 200, 25 * time.Hour}}, amount: 100000},
+		{name: "between spaces", entries: []entry{{auditSpaceTransfer, 60000, 200, time.Hour}}, amount: 100000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auditHome(t)
+
+			for _, e := range tt.entries {
+				audited(t, e.action, e.amount, "EUR", e.status, e.age)
+			}
+
+			meta := &cli.Metadata{Config: &cli.Config{Limits: cli.Limits{MaxDaily: 100000}}}
+			transfers := cli.BatchTransferList{{Recipient: "john@example.com", Amount: tt.amount}}
+
+			if err := checkLimits(meta, transfers, true, "EUR"); (err != nil) != tt.err {
+				t.Errorf("checkLimits() = %v, want error: %v", err, tt.err)
+			}
+		})
+	}
+}
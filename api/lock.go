@@ -0,0 +1,115 @@
+package api
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/oauth2"
+)
+
+// lockCredentials prevents several processes from refreshing or replacing
+// the credentials at the same time. It must not be called while holding the
+// lock, since the lock is not reentrant.
+func lockCredentials() (func(), error) {
+	return lockFile("auth.lock", "credentials")
+}
+
+// lockAudit prevents several processes from appending to the audit log at
+// the same time, which would chain two entries to the same one.
+func lockAudit() (func(), error) {
+	return lockFile("audit.lock", "audit log")
+}
+
+// lockBatch prevents a batch from being run by several processes at the
+// same time, which would both read the same progress and pay every row
+// twice.
+func lockBatch(id string) (func(), error) {
+	return lockFile(fmt.Sprintf("batch-%s.lock", id), "batch journal")
+}
+
+func lockFile(name, what string) (func(), error) {
+	path := configFile(name)
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("could not open lock file at '%s'", path)
+	}
+
+	if err := lockHandle(file); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("could not lock %s", what)
+	}
+
+	return func() {
+		unlockHandle(file)
+		file.Close()
+	}, nil
+}
+
+func writeFileAtomic(path string, data []byte) error {
+	return publishFile(path, data, os.Rename)
+}
+
+// linkFile atomically creates a file with the given content, failing if it
+// already exists.
+func linkFile(path string, data []byte) error {
+	return publishFile(path, data, os.Link)
+}
+
+func publishFile(path string, data []byte, publish func(string, string) error) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), fmt.Sprintf(".%s.", filepath.Base(path)))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return publish(tmp.Name(), path)
+}
+
+// sharedTokenSource refreshes the token on behalf of every process using
+// the same credentials: whoever gets the lock first refreshes the token, and
+// the others pick the new token up from the store.
+type sharedTokenSource struct {
+	config oauth2.Config
+}
+
+func (s *sharedTokenSource) Token() (*oauth2.Token, error) {
+	unlock, err := lockCredentials()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	creds, err := LoadCredentials()
+	if err == errWrongPassphrase {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("credentials have expired, please try again")
+	}
+
+	if token := creds.Token(); token.Valid() {
+		return token, nil
+	}
+
+	token, err := s.config.TokenSource(authContext(), &oauth2.Token{RefreshToken: creds.RefreshToken}).Token()
+	if err != nil {
+		return nil, err
+	}
+
+	return SaveCredentials(token, tokenExpiry(token)).Token(), nil
+}
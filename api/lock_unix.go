@@ -0,0 +1,16 @@
+//go:build unix
+
+package api
+
+import (
+	"os"
+	"syscall"
+)
+
+func lockHandle(file *os.File) error {
+	return syscall.Flock(int(file.Fd()), syscall.LOCK_EX)
+}
+
+func unlockHandle(file *os.File) error {
+	return syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+}
@@ -0,0 +1,37 @@
+//go:build windows
+
+package api
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	kernel32         = syscall.NewLazyDLL("kernel32.dll")
+	procLockFileEx   = kernel32.NewProc("LockFileEx")
+	procUnlockFileEx = kernel32.NewProc("UnlockFileEx")
+)
+
+const lockfileExclusiveLock = 0x2
+
+// lockHandle locks the first byte of the file, which is enough for the lock
+// files, since they are never written to.
+func lockHandle(file *os.File) error {
+	overlapped := new(syscall.Overlapped)
+
+	if r, _, err := procLockFileEx.Call(file.Fd(), lockfileExclusiveLock, 0, 1, 0, uintptr(unsafe.Pointer(overlapped))); r == 0 {
+		return err
+	}
+	return nil
+}
+
+func unlockHandle(file *os.File) error {
+	overlapped := new(syscall.Overlapped)
+
+	if r, _, err := procUnlockFileEx.Call(file.Fd(), 0, 1, 0, uintptr(unsafe.Pointer(overlapped))); r == 0 {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,178 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/apognu/n26/cli"
+	"golang.org/x/oauth2"
+)
+
+var (
+	mfaPollInterval = 2 * time.Second
+	mfaPollTimeout  = 5 * time.Minute
+)
+
+var errAuthorizationPending = fmt.Errorf("authorization is pending")
+
+type oauthError struct {
+	Error       string `json:"error"`
+	Description string `json:"error_description"`
+	MFAToken    string `json:"mfaToken"`
+}
+
+type oauthToken struct {
+	TokenType    string `json:"token_type"`
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"`
+	// Only sent by some versions of the API
+	RefreshExpiresIn int64 `json:"refresh_token_expires_in"`
+}
+
+// mfaRequired extracts the MFA token from a password grant that was refused
+// because a second factor is needed.
+func mfaRequired(err error) (string, bool) {
+	e, ok := err.(*oauth2.RetrieveError)
+	if !ok {
+		return "", false
+	}
+
+	body := new(oauthError)
+	if err := json.Unmarshal(e.Body, body); err != nil || body.Error != "mfa_required" || body.MFAToken == "" {
+		return "", false
+	}
+
+	return body.MFAToken, true
+}
+
+// errMFANotInteractive is returned instead of waiting for an answer on an
+// input that is not a terminal, such as when running unattended.
+var errMFANotInteractive = fmt.Errorf("two-factor authentication is required but the standard input is not a terminal, run 'n26 auth login' interactively first")
+
+var interactive = cli.Interactive
+
+func mfaLogin(mfaToken string) (*oauth2.Token, error) {
+	if !interactive() {
+		return nil, errMFANotInteractive
+	}
+
+	switch cli.ReadLine("Two-factor authentication is required, confirm from your paired device (1) or with an SMS code (2)? [1]") {
+	case "", "1":
+		return mfaOOB(mfaToken, func() {
+			cli.Info("Please confirm the login from your paired device")
+		})
+
+	case "2":
+		return mfaOTP(mfaToken, func() string {
+			return strings.TrimSpace(cli.ReadLine("SMS code:"))
+		})
+
+	default:
+		return nil, fmt.Errorf("unknown two-factor authentication method")
+	}
+}
+
+// mfaOOB asks for a confirmation on the paired device and waits for it.
+func mfaOOB(mfaToken string, prompt func()) (*oauth2.Token, error) {
+	if err := mfaChallenge(mfaToken, "oob"); err != nil {
+		return nil, err
+	}
+
+	prompt()
+
+	form := url.Values{}
+	form.Set("grant_type", "mfa_oob")
+	form.Set("mfaToken", mfaToken)
+
+	for start := time.Now(); time.Since(start) < mfaPollTimeout; time.Sleep(mfaPollInterval) {
+		token, err := exchangeToken(form)
+		if err == errAuthorizationPending {
+			continue
+		}
+
+		return token, err
+	}
+
+	return nil, fmt.Errorf("the login was not confirmed in time")
+}
+
+// mfaOTP has a code sent by SMS and logs in with the code read by prompt.
+func mfaOTP(mfaToken string, prompt func() string) (*oauth2.Token, error) {
+	if err := mfaChallenge(mfaToken, "otp"); err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "mfa_otp")
+	form.Set("mfaToken", mfaToken)
+	form.Set("otp", prompt())
+
+	return exchangeToken(form)
+}
+
+func mfaChallenge(mfaToken, challengeType string) error {
+	body, err := json.Marshal(map[string]string{
+		"challengeType": challengeType,
+		"mfaToken":      mfaToken,
+	})
+	if err != nil {
+		return fmt.Errorf("could not marshal request")
+	}
+
+	resp, err := authRequest("/api/mfa/challenge", "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode > 399 {
+		return fmt.Errorf("could not request a two-factor authentication challenge")
+	}
+
+	return nil
+}
+
+func exchangeToken(form url.Values) (*oauth2.Token, error) {
+	resp, err := authRequest("/oauth/token", "application/x-www-form-urlencoded", strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode > 399 {
+		body := new(oauthError)
+		json.NewDecoder(resp.Body).Decode(body)
+
+		if body.Error == "authorization_pending" {
+			return nil, errAuthorizationPending
+		}
+		if body.Description != "" {
+			return nil, fmt.Errorf("%s", body.Description)
+		}
+		return nil, fmt.Errorf("two-factor authentication failed with status %d", resp.StatusCode)
+	}
+
+	body := new(oauthToken)
+	if err := json.NewDecoder(resp.Body).Decode(body); err != nil {
+		return nil, fmt.Errorf("could not unmarshal upstream data")
+	}
+
+	token := &oauth2.Token{
+		TokenType:    body.TokenType,
+		AccessToken:  body.AccessToken,
+		RefreshToken: body.RefreshToken,
+	}
+	if body.ExpiresIn > 0 {
+		token.Expiry = time.Now().Add(time.Duration(body.ExpiresIn) * time.Second)
+	}
+	if body.RefreshExpiresIn > 0 {
+		token = token.WithExtra(map[string]interface{}{"refresh_token_expires_in": float64(body.RefreshExpiresIn)})
+	}
+
+	return token, nil
+}
@@ -0,0 +1,254 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// fakeN26 points the client at a local server for the duration of a test.
+func fakeN26(t *testing.T, handler http.HandlerFunc) {
+	server := httptest.NewServer(handler)
+
+	previousURL, previousDevice := baseURL, deviceToken
+	baseURL, deviceToken = server.URL, "00000000-0000-4000-8000-000000000000"
+
+	t.Cleanup(func() {
+		baseURL, deviceToken = previousURL, previousDevice
+		server.Close()
+	})
+}
+
+func reply(w http.ResponseWriter, status int, body string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	fmt.Fprint(w, body)
+}
+
+const (
+	mfaPendingBody = `{"error":"authorization_pending","error_description":"The login has not been confirmed yet"}`
+	mfaTokenBody   = `{"token_type":"bearer","access_token":"access","refresh_token":"refresh","expires_in":1800}`
+)
+
+func TestMFARequired(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		token  string
+		ok     bool
+	}{
+		{"mfa required", 403, `{"error":"mfa_required","mfaToken":"mfa-token"}`, "mfa-token", true},
+		{"mfa required without token", 403, `{"error":"mfa_required"}`, "", false},
+		{"bad credentials", 400, `{"error":"invalid_grant","error_description":"Bad credentials"}`, "", false},
+		{"server error", 500, `oops`, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fakeN26(t, func(w http.ResponseWriter, r *http.Request) {
+				reply(w, tt.status, tt.body)
+			})
+
+			c := oauthConfig()
+
+			_, err := c.PasswordCredentialsToken(authContext(), "user@example.com", "password")
+			if err == nil {
+				t.Fatal("expected the password grant to fail")
+			}
+
+			token, ok := mfaRequired(err)
+			if token != tt.token || ok != tt.ok {
+				t.Errorf("mfaRequired() = (%q, %v), want (%q, %v)", token, ok, tt.token, tt.ok)
+			}
+		})
+	}
+}
+
+func TestMFAOOB(t *testing.T) {
+	tests := []struct {
+		name      string
+		challenge int
+		responses []string
+		timeout   time.Duration
+		err       string
+	}{
+		{"confirmed", 200, []string{mfaPendingBody, mfaPendingBody, mfaTokenBody}, time.Second, ""},
+		{"not confirmed in time", 200, []string{mfaPendingBody}, 20 * time.Millisecond, "the login was not confirmed in time"},
+		{"refused", 200, []string{`{"error":"access_denied","error_description":"The login was refused"}`}, time.Second, "The login was refused"},
+		{"challenge failed", 403, nil, time.Second, "could not request a two-factor authentication challenge"},
+	}
+
+	defer func(interval, timeout time.Duration) {
+		mfaPollInterval, mfaPollTimeout = interval, timeout
+	}(mfaPollInterval, mfaPollTimeout)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mfaPollInterval, mfaPollTimeout = time.Millisecond, tt.timeout
+
+			polls := 0
+			fakeN26(t, func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/api/mfa/challenge":
+					body := make(map[string]string)
+					json.NewDecoder(r.Body).Decode(&body)
+
+					if body["challengeType"] != "oob" || body["mfaToken"] != "mfa-token" {
+						t.Errorf("unexpected challenge %v", body)
+					}
+
+					reply(w, tt.challenge, `{}`)
+
+				case "/oauth/token":
+					if r.FormValue("grant_type") != "mfa_oob" || r.FormValue("mfaToken") != "mfa-token" {
+						t.Errorf("unexpected token request %v", r.Form)
+					}
+
+					response := tt.responses[len(tt.responses)-1]
+					if polls < len(tt.responses) {
+						response = tt.responses[polls]
+					}
+					polls++
+
+					if response == mfaTokenBody {
+						reply(w, 200, response)
+					} else {
+						reply(w, 400, response)
+					}
+				}
+			})
+
+			prompted := false
+			token, err := mfaOOB("mfa-token", func() { prompted = true })
+
+			if tt.err != "" {
+				if err == nil || err.Error() != tt.err {
+					t.Fatalf("mfaOOB() error = %v, want %q", err, tt.err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("mfaOOB() error = %v", err)
+			}
+			if !prompted {
+				t.Error("the user was not asked to confirm the login")
+			}
+			if token.AccessToken != "access" || polls != len(tt.responses) {
+				t.Errorf("mfaOOB() = %q after %d polls, want %q after %d polls", token.AccessToken, polls, "access", len(tt.responses))
+			}
+		})
+	}
+}
+
+func TestMFAOTP(t *testing.T) {
+	tests := []struct {
+		name string
+		otp  string
+		err  string
+	}{
+		{"valid code", "123456", ""},
+		{"invalid code", "654321", "The code is invalid"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fakeN26(t, func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/api/mfa/challenge":
+					body := make(map[string]string)
+					json.NewDecoder(r.Body).Decode(&body)
+
+					if body["challengeType"] != "otp" {
+						t.Errorf("unexpected challenge type %q", body["challengeType"])
+					}
+
+					reply(w, 201, `{}`)
+
+				case "/oauth/token":
+					if r.FormValue("grant_type") != "mfa_otp" || r.FormValue("otp") != "123456" {
+						reply(w, 400, `{"error":"invalid_otp","error_description":"The code is invalid"}`)
+						return
+					}
+
+					reply(w, 200, mfaTokenBody)
+				}
+			})
+
+			token, err := mfaOTP("mfa-token", func() string { return tt.otp })
+
+			if tt.err != "" {
+				if err == nil || err.Error() != tt.err {
+					t.Fatalf("mfaOTP() error = %v, want %q", err, tt.err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("mfaOTP() error = %v", err)
+			}
+			if token.AccessToken != "access" || token.RefreshToken != "refresh" || token.Expiry.IsZero() {
+				t.Errorf("mfaOTP() = %+v", token)
+			}
+		})
+	}
+}
+
+func TestExchangeTokenErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		err    string
+	}{
+		{"pending", 400, mfaPendingBody, errAuthorizationPending.Error()},
+		{"described error", 401, `{"error":"invalid_grant","error_description":"Bad credentials"}`, "Bad credentials"},
+		{"undescribed error", 500, `oops`, "two-factor authentication failed with status 500"},
+		{"invalid token", 200, `oops`, "could not unmarshal upstream data"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fakeN26(t, func(w http.ResponseWriter, r *http.Request) {
+				reply(w, tt.status, tt.body)
+			})
+
+			_, err := exchangeToken(nil)
+			if err == nil || err.Error() != tt.err {
+				t.Errorf("exchangeToken() error = %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestMFANotInteractive(t *testing.T) {
+	defer SetCredentialsProvider(credentialsProvider)
+	SetCredentialsProvider(func() (string, string, error) {
+		return "user@example.com", "password", nil
+	})
+
+	defer func(previous func() bool) {
+		interactive = previous
+	}(interactive)
+	interactive = func() bool { return false }
+
+	challenged := false
+	fakeN26(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/mfa/challenge" {
+			challenged = true
+		}
+
+		reply(w, 403, `{"error":"mfa_required","mfaToken":"mfa-token"}`)
+	})
+
+	if _, err := login(oauthConfig()); err != errMFANotInteractive {
+		t.Errorf("login() error = %v, want %v", err, errMFANotInteractive)
+	}
+	if challenged {
+		t.Error("a two-factor challenge was requested without anyone to answer it")
+	}
+}
@@ -4,43 +4,31 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
-	"time"
 
 	"github.com/apognu/n26/cli"
 )
 
 func (cl *N26Client) GetPastTransactions(meta *cli.Metadata, from, to string, limit int) (cli.PastTransactionList, error) {
-	now := time.Now()
-	defaultFrom := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.Local)
-	defaultTo := defaultFrom.AddDate(0, 1, 0).Add(-time.Nanosecond)
+	period, err := ParsePeriod(from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	return cl.getTransactions(period, limit)
+}
 
+func (cl *N26Client) getTransactions(period Period, limit int) (cli.PastTransactionList, error) {
 	req := &N26Request{
 		Method:  http.MethodGet,
 		Path:    "/api/smrt/transactions",
 		Decoder: NewJSON(new(cli.PastTransactionList)),
 		Params: map[string]string{
-			"from": fmt.Sprintf("%d", defaultFrom.Unix()*1000),
-			"to":   fmt.Sprintf("%d", defaultTo.Unix()*1000),
+			"from":  fmt.Sprint(cli.Millis(period.From)),
+			"to":    fmt.Sprint(cli.Millis(period.To)),
+			"limit": fmt.Sprint(limit),
 		},
 	}
 
-	req.Params["limit"] = fmt.Sprintf("%d", limit)
-
-	if from != "" {
-		if to == "" {
-			return nil, fmt.Errorf("both 'from' and 'to' must be provided")
-		}
-
-		f, ferr := time.Parse("2006-01-02", from)
-		t, terr := time.Parse("2006-01-02", to)
-		if ferr != nil || terr != nil {
-			return nil, fmt.Errorf("could not parse provided dates")
-		}
-
-		req.Params["from"] = fmt.Sprint(f.Unix() * 1000)
-		req.Params["to"] = fmt.Sprint(t.Unix() * 1000)
-	}
-
 	output, err := cl.Request(req, false)
 	if err != nil {
 		return nil, err
@@ -93,18 +81,29 @@ func (cl *N26Client) CheckContact(id string) bool {
 	return true
 }
 
-func (cl *N26Client) CreateSpaceTransfer(meta *cli.Metadata, from, to string, amount float64) (cli.SimpleMessage, error) {
+func (cl *N26Client) CreateSpaceTransfer(meta *cli.Metadata, from, to string, amount cli.Amount) (cli.Printable, error) {
 	spaces, err := cl.GetSpaces(meta)
 	if err != nil {
-		return "", fmt.Errorf("could not get your spaces")
+		return nil, fmt.Errorf("could not get your spaces")
 	}
 
 	fromSpace, toSpace := getSpaceFromID(spaces, from), getSpaceFromID(spaces, to)
 	if fromSpace == nil || toSpace == nil {
-		return "", fmt.Errorf("could not find the provided spaces")
+		return nil, fmt.Errorf("could not find the provided spaces")
 	}
 
-	cli.ConfirmSpaceTransfer(fromSpace, toSpace, amount)
+	if err := amount.Check(fromSpace.Balance.Currency); err != nil {
+		return nil, err
+	}
+
+	if amount > fromSpace.Balance.AvailableBalance {
+		return nil, fmt.Errorf("the space '%s' only holds %s", fromSpace.Name, cli.Curr(fromSpace.Balance.AvailableBalance, fromSpace.Balance.Currency))
+	}
+
+	transfers := cli.BatchTransferList{{Recipient: toSpace.Name, Amount: amount}}
+	if err := checkLimits(meta, transfers, false, fromSpace.Balance.Currency); err != nil {
+		return nil, err
+	}
 
 	trx := cli.SpaceTransaction{
 		FromSpaceID: fromSpace.ID,
@@ -118,17 +117,28 @@ func (cl *N26Client) CreateSpaceTransfer(meta *cli.Metadata, from, to string, am
 		Body:   trx,
 	}
 
+	cli.ConfirmSpaceTransfer(meta, fromSpace, toSpace, amount)
+	confirmAmounts(meta, transfers, fromSpace.Balance.Currency)
+
+	if meta.DryRun {
+		return dryRun(req), nil
+	}
+
 	_, err = cl.Request(req, false)
+	msg := (cli.SimpleMessage)(fmt.Sprintf("Your transfer of %s has been performed.", cli.Curr(amount, fromSpace.Balance.Currency)))
+
+	recordAudit(auditSpaceTransfer, toSpace.Name, cli.Money{Amount: amount, Currency: fromSpace.Balance.Currency}, req, string(msg), err)
+
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	return (cli.SimpleMessage)(fmt.Sprintf("Your transfer of %s has been performed.", cli.Curr(amount, fromSpace.Balance.Currency))), nil
+	return msg, nil
 }
 
-func (cl *N26Client) CreateMoneyBeam(meta *cli.Metadata, name, recipient string, amount float64, comment string) (cli.SimpleMessage, error) {
+func (cl *N26Client) CreateMoneyBeam(meta *cli.Metadata, name, recipient string, amount cli.Amount, comment string) (cli.Printable, error) {
 	if !cl.CheckContact(recipient) {
-		return "", fmt.Errorf("the provided recipient ID is not associated with an N26 account")
+		return nil, fmt.Errorf("the provided recipient ID is not associated with an N26 account")
 	}
 
 	details := cli.MoneyBeamDetails{Type: "FT", PartnerName: name, Amount: amount, Comment: comment}
@@ -142,23 +152,28 @@ func (cl *N26Client) CreateMoneyBeam(meta *cli.Metadata, name, recipient string,
 	} else if strings.HasPrefix(recipient, "+") {
 		details.PartnerPhone = recipient
 	} else {
-		return "", fmt.Errorf("the recipient must be an email address or a phone number (starting with '+')")
+		return nil, fmt.Errorf("the recipient must be an email address or a phone number (starting with '+')")
 	}
 
 	balance, err := cl.GetBalance(meta)
 	if err != nil {
-		return "", fmt.Errorf("could not get current balance")
+		return nil, fmt.Errorf("could not get current balance")
+	}
+
+	if err := amount.Check(balance.Currency); err != nil {
+		return nil, err
 	}
 
-	cli.ConfirmMoneyBeam(details, balance)
+	if amount > balance.UsageBalance {
+		return nil, fmt.Errorf("your usable balance is only %s", cli.Curr(balance.UsageBalance, balance.Currency))
+	}
 
-	pin, err := cli.ReadSecret("Enter your PIN:")
-	if err != nil {
-		return "", fmt.Errorf("could not read PIN")
+	transfers := cli.BatchTransferList{{Recipient: recipient, Amount: amount}}
+	if err := checkLimits(meta, transfers, true, balance.Currency); err != nil {
+		return nil, err
 	}
 
 	trx := cli.MoneyBeam{
-		PIN:         string(pin),
 		Transaction: details,
 	}
 
@@ -168,10 +183,28 @@ func (cl *N26Client) CreateMoneyBeam(meta *cli.Metadata, name, recipient string,
 		Body:   trx,
 	}
 
+	cli.ConfirmMoneyBeam(meta, details, balance)
+	confirmAmounts(meta, transfers, balance.Currency)
+
+	trx.PIN, err = readPIN(meta)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Body = trx
+
+	if meta.DryRun {
+		return dryRun(req), nil
+	}
+
 	_, err = cl.Request(req, false)
+	msg := (cli.SimpleMessage)(fmt.Sprintf("Your transfer of %s has been requested, please confirm from your paired device.", cli.Curr(trx.Transaction.Amount, balance.Currency)))
+
+	recordAudit(auditMoneyBeam, recipient, cli.Money{Amount: amount, Currency: balance.Currency}, req, string(msg), err)
+
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	return (cli.SimpleMessage)(fmt.Sprintf("Your transfer of %s has been requested, please confirm from your paired device.", cli.Curr(trx.Transaction.Amount, balance.Currency))), nil
+	return msg, nil
 }
@@ -0,0 +1,180 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/apognu/n26/cli"
+)
+
+var (
+	passphrase         string
+	passphraseProvided bool
+)
+
+// SetPassphrase provides the passphrase protecting the encrypted credentials
+// file, instead of asking for it.
+func SetPassphrase(p string) {
+	passphrase, passphraseProvided = p, true
+}
+
+// SetPassphraseFD reads the passphrase protecting the encrypted credentials
+// file from the first line of a file descriptor.
+func SetPassphraseFD(fd int) error {
+	p, err := readLine(fd)
+	if err != nil {
+		return fmt.Errorf("could not read passphrase from file descriptor %d", fd)
+	}
+
+	passphrase, passphraseProvided = p, true
+
+	return nil
+}
+
+func readPassphrase(confirm bool) (string, error) {
+	if passphrase != "" {
+		return passphrase, nil
+	}
+	if p := os.Getenv("N26_PASSPHRASE"); p != "" {
+		passphraseProvided = true
+		return p, nil
+	}
+
+	p, err := cli.ReadSecret("Credentials passphrase:")
+	if err != nil {
+		return "", fmt.Errorf("could not read passphrase")
+	}
+
+	if confirm {
+		c, err := cli.ReadSecret("Confirm credentials passphrase:")
+		if err != nil {
+			return "", fmt.Errorf("could not read passphrase")
+		}
+		if p != c {
+			return "", fmt.Errorf("the passphrases do not match")
+		}
+	}
+
+	passphrase = p
+
+	return p, nil
+}
+
+// forgetPassphrase discards a passphrase that could not decrypt the
+// credentials, and tells whether it can be asked for again.
+func forgetPassphrase() bool {
+	if passphraseProvided {
+		return false
+	}
+
+	passphrase = ""
+
+	return true
+}
+
+type sessionKeyFile struct {
+	Salt    []byte    `json:"salt"`
+	Key     []byte    `json:"key"`
+	Expires time.Time `json:"expires"`
+}
+
+// When enabled with passphrase_cache, the derived key is kept in the user's
+// runtime directory for that long, so the passphrase is not asked for every
+// command. Anyone able to read that file can decrypt the credentials.
+func sessionKeyLifetime() time.Duration {
+	if lifetime, err := time.ParseDuration(config.Credentials.PassphraseCache); err == nil && lifetime > 0 {
+		return lifetime
+	}
+	return 0
+}
+
+func sessionKeyPath() string {
+	dir := os.Getenv("XDG_RUNTIME_DIR")
+	if dir == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s/n26.%s.key", dir, profile)
+}
+
+func sessionKey(salt []byte) []byte {
+	if sessionKeyPath() == "" {
+		return nil
+	}
+	if sessionKeyLifetime() == 0 {
+		clearSessionKey()
+		return nil
+	}
+
+	data, err := ioutil.ReadFile(sessionKeyPath())
+	if err != nil {
+		return nil
+	}
+
+	file := new(sessionKeyFile)
+	if err := json.Unmarshal(data, file); err != nil || time.Now().After(file.Expires) {
+		clearSessionKey()
+		return nil
+	}
+	if !bytes.Equal(file.Salt, salt) {
+		return nil
+	}
+
+	return file.Key
+}
+
+// saveSessionKey caches the derived key, unless it is already cached, so it
+// expires at the end of the lifetime that started when it was first cached.
+func saveSessionKey(salt, key []byte) {
+	if sessionKeyPath() == "" || sessionKeyLifetime() == 0 || bytes.Equal(sessionKey(salt), key) {
+		return
+	}
+
+	data, err := json.Marshal(sessionKeyFile{Salt: salt, Key: key, Expires: time.Now().Add(sessionKeyLifetime())})
+	if err != nil {
+		return
+	}
+
+	ioutil.WriteFile(sessionKeyPath(), data, 0600)
+}
+
+func clearSessionKey() {
+	if sessionKeyPath() != "" {
+		os.Remove(sessionKeyPath())
+	}
+}
+
+// Credentials stored in plain text before a store was configured are moved
+// into the configured store.
+func migrateCredentials() ([]byte, error) {
+	if _, ok := credentialStore().(fileStore); ok {
+		return nil, fmt.Errorf("no plain text credentials to migrate")
+	}
+
+	data, err := ioutil.ReadFile(ConfigPath())
+	if err != nil {
+		return nil, err
+	}
+
+	creds := new(Credentials)
+	if err := json.Unmarshal(data, creds); err != nil || creds.RefreshToken == "" {
+		return nil, fmt.Errorf("no plain text credentials to migrate")
+	}
+
+	if err := credentialStore().Save(data); err != nil {
+		return nil, err
+	}
+
+	if _, ok := credentialStore().(*encryptedFileStore); !ok {
+		if err := os.Remove(ConfigPath()); err != nil {
+			return nil, fmt.Errorf("could not delete plain text credentials file at '%s'", ConfigPath())
+		}
+	}
+
+	cli.Warn(fmt.Errorf("plain text credentials at '%s' have been moved to the configured credential store", ConfigPath()))
+
+	return data, nil
+}
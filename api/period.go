@@ -0,0 +1,153 @@
+package api
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/apognu/n26/cli"
+)
+
+var (
+	relativeDate = regexp.MustCompile(`^(?:(\d+)([dwmy])-ago|-(\d+)([dwmy]))$`)
+	quarterDate  = regexp.MustCompile(`^(\d{4})-[Qq]([1-4])$`)
+	yearDate     = regexp.MustCompile(`^\d{4}$`)
+	monthDate    = regexp.MustCompile(`^\d{4}-\d{2}$`)
+)
+
+// Period is a range of time whose bounds are both included. Bounds are
+// precise to the millisecond, which is the resolution of the N26 API.
+type Period struct {
+	From time.Time
+	To   time.Time
+}
+
+func (period Period) Contains(t time.Time) bool {
+	return !t.Before(period.From) && !t.After(period.To)
+}
+
+// ParsePeriod parses the bounds of a period in the configured timezone.
+func ParsePeriod(from, to string) (Period, error) {
+	return parsePeriod(from, to, time.Now().In(cli.Location))
+}
+
+// parsePeriod parses the bounds of a period. Each bound can be a date
+// (2024-05-17), a month (2024-05), a quarter (2024-Q3), a year (2024), a
+// number of days, weeks, months or years ago (30d-ago, 2w-ago, 6m-ago, 1y-ago,
+// or -30d, -2w, -6m, -1y) or one of
+// today, yesterday, this-week, last-week, this-month, last-month, this-year
+// and last-year.
+//
+// The period starts at the beginning of from and ends at the end of to, so
+// '--from 2024-05 --to 2024-05' covers the whole month of May. Without from,
+// the period starts at the beginning of time, without to it ends now, and
+// without either it covers the current month.
+func parsePeriod(from, to string, now time.Time) (Period, error) {
+	if from == "" && to == "" {
+		from = "this-month"
+		to = "this-month"
+	}
+
+	period := Period{From: time.Unix(0, 0).In(now.Location()), To: now}
+
+	if from != "" {
+		start, _, err := parseDate(from, now)
+		if err != nil {
+			return period, err
+		}
+		period.From = start
+	}
+
+	if to != "" {
+		_, end, err := parseDate(to, now)
+		if err != nil {
+			return period, err
+		}
+		period.To = end
+	}
+
+	if period.From.After(period.To) {
+		return period, fmt.Errorf("the period cannot start after it ends")
+	}
+
+	return period, nil
+}
+
+// parseDate returns the first and last milliseconds of the range of time
+// designated by a date expression. Days are calendar days in the location
+// of now, so they last 23 or 25 hours when daylight saving time changes.
+func parseDate(expr string, now time.Time) (time.Time, time.Time, error) {
+	loc := now.Location()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	weekday := (int(today.Weekday()) + 6) % 7
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, loc)
+	year := time.Date(now.Year(), 1, 1, 0, 0, 0, 0, loc)
+
+	switch expr {
+	case "today":
+		return span(today, 0, 0, 1)
+	case "yesterday":
+		return span(today.AddDate(0, 0, -1), 0, 0, 1)
+	case "this-week":
+		return span(today.AddDate(0, 0, -weekday), 0, 0, 7)
+	case "last-week":
+		return span(today.AddDate(0, 0, -weekday-7), 0, 0, 7)
+	case "this-month":
+		return span(month, 0, 1, 0)
+	case "last-month":
+		return span(month.AddDate(0, -1, 0), 0, 1, 0)
+	case "this-year":
+		return span(year, 1, 0, 0)
+	case "last-year":
+		return span(year.AddDate(-1, 0, 0), 1, 0, 0)
+	}
+
+	if m := relativeDate.FindStringSubmatch(expr); m != nil {
+		// Only one of the two forms matched, the other one is empty.
+		n, _ := strconv.Atoi(m[1] + m[3])
+		day := today
+
+		switch m[2] + m[4] {
+		case "d":
+			day = today.AddDate(0, 0, -n)
+		case "w":
+			day = today.AddDate(0, 0, -7*n)
+		case "m":
+			day = today.AddDate(0, -n, 0)
+		case "y":
+			day = today.AddDate(-n, 0, 0)
+		}
+
+		return span(day, 0, 0, 1)
+	}
+
+	if m := quarterDate.FindStringSubmatch(expr); m != nil {
+		y, _ := strconv.Atoi(m[1])
+		q, _ := strconv.Atoi(m[2])
+
+		return span(time.Date(y, time.Month(3*q-2), 1, 0, 0, 0, 0, loc), 0, 3, 0)
+	}
+
+	if yearDate.MatchString(expr) {
+		if t, err := time.ParseInLocation("2006", expr, loc); err == nil {
+			return span(t, 1, 0, 0)
+		}
+	}
+
+	if monthDate.MatchString(expr) {
+		if t, err := time.ParseInLocation("2006-01", expr, loc); err == nil {
+			return span(t, 0, 1, 0)
+		}
+	}
+
+	if t, err := time.ParseInLocation("2006-01-02", expr, loc); err == nil {
+		return span(t, 0, 0, 1)
+	}
+
+	return now, now, fmt.Errorf("could not parse date '%s'", expr)
+}
+
+func span(start time.Time, years, months, days int) (time.Time, time.Time, error) {
+	return start, start.AddDate(years, months, days).Add(-time.Millisecond), nil
+}
@@ -0,0 +1,159 @@
+package api
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata"
+
+	"github.com/apognu/n26/cli"
+)
+
+const periodLayout = "2006-01-02T15:04:05.000Z07:00"
+
+func TestParsePeriodDST(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	at := func(s string) time.Time {
+		now, err := time.ParseInLocation("2006-01-02 15:04", s, berlin)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return now
+	}
+
+	tests := []struct {
+		name     string
+		from, to string
+		now      time.Time
+		start    string
+		end      string
+		duration time.Duration
+	}{
+		{
+			name: "23-hour day", from: "2018-03-25", to: "2018-03-25", now: at("2018-11-15 12:00"),
+			start: "2018-03-25T00:00:00.000+01:00", end: "2018-03-25T23:59:59.999+02:00",
+			duration: 23*time.Hour - time.Millisecond,
+		},
+		{
+			name: "25-hour day", from: "2018-10-28", to: "2018-10-28", now: at("2018-11-15 12:00"),
+			start: "2018-10-28T00:00:00.000+02:00", end: "2018-10-28T23:59:59.999+01:00",
+			duration: 25*time.Hour - time.Millisecond,
+		},
+		{
+			name: "today on a 23-hour day", from: "today", to: "today", now: at("2024-03-31 18:00"),
+			start: "2024-03-31T00:00:00.000+01:00", end: "2024-03-31T23:59:59.999+02:00",
+			duration: 23*time.Hour - time.Millisecond,
+		},
+		{
+			name: "yesterday was a 25-hour day", from: "yesterday", to: "yesterday", now: at("2024-10-28 08:00"),
+			start: "2024-10-27T00:00:00.000+02:00", end: "2024-10-27T23:59:59.999+01:00",
+			duration: 25*time.Hour - time.Millisecond,
+		},
+		{
+			name: "inclusive end date", from: "2018-01-01", to: "2018-01-31", now: at("2018-11-15 12:00"),
+			start: "2018-01-01T00:00:00.000+01:00", end: "2018-01-31T23:59:59.999+01:00",
+			duration: 31*24*time.Hour - time.Millisecond,
+		},
+		{
+			name: "last-month over the spring change", from: "last-month", to: "last-month", now: at("2018-04-10 12:00"),
+			start: "2018-03-01T00:00:00.000+01:00", end: "2018-03-31T23:59:59.999+02:00",
+			duration: 31*24*time.Hour - time.Hour - time.Millisecond,
+		},
+		{
+			name: "this-month over the autumn change", from: "this-month", to: "this-month", now: at("2018-10-28 12:00"),
+			start: "2018-10-01T00:00:00.000+02:00", end: "2018-10-31T23:59:59.999+01:00",
+			duration: 31*24*time.Hour + time.Hour - time.Millisecond,
+		},
+		{
+			name: "quarter ending in summer time", from: "2018-Q1", to: "2018-Q1", now: at("2018-11-15 12:00"),
+			start: "2018-01-01T00:00:00.000+01:00", end: "2018-03-31T23:59:59.999+02:00",
+			duration: 90*24*time.Hour - time.Hour - time.Millisecond,
+		},
+		{
+			name: "month of the autumn change", from: "2018-10", to: "2018-10", now: at("2018-11-15 12:00"),
+			start: "2018-10-01T00:00:00.000+02:00", end: "2018-10-31T23:59:59.999+01:00",
+			duration: 31*24*time.Hour + time.Hour - time.Millisecond,
+		},
+		{
+			name: "one year ago until now", from: "-1y", now: at("2018-07-01 12:30"),
+			start: "2017-07-01T00:00:00.000+02:00", end: "2018-07-01T12:30:00.000+02:00",
+			duration: 365*24*time.Hour + 12*time.Hour + 30*time.Minute,
+		},
+		{
+			name: "one year ago from winter time", from: "1y-ago", to: "today", now: at("2018-01-15 09:00"),
+			start: "2017-01-15T00:00:00.000+01:00", end: "2018-01-15T23:59:59.999+01:00",
+			duration: 366*24*time.Hour - time.Millisecond,
+		},
+		{
+			name: "days ago over the spring change", from: "30d-ago", to: "today", now: at("2018-04-10 12:00"),
+			start: "2018-03-11T00:00:00.000+01:00", end: "2018-04-10T23:59:59.999+02:00",
+			duration: 31*24*time.Hour - time.Hour - time.Millisecond,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			period, err := parsePeriod(tt.from, tt.to, tt.now)
+			if err != nil {
+				t.Fatalf("parsePeriod(%q, %q) error = %v", tt.from, tt.to, err)
+			}
+
+			if start := period.From.Format(periodLayout); start != tt.start {
+				t.Errorf("period starts at %s, want %s", start, tt.start)
+			}
+			if end := period.To.Format(periodLayout); end != tt.end {
+				t.Errorf("period ends at %s, want %s", end, tt.end)
+			}
+			if duration := period.To.Sub(period.From); duration != tt.duration {
+				t.Errorf("period lasts %s, want %s", duration, tt.duration)
+			}
+		})
+	}
+}
+
+func TestPeriodMillis(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer func(loc *time.Location) { cli.Location = loc }(cli.Location)
+	cli.Location = berlin
+
+	period, err := parsePeriod("2018-10-28", "2018-10-28", time.Date(2018, 11, 15, 12, 0, 0, 0, berlin))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		ms       int64
+		contains bool
+	}{
+		{"last millisecond of the previous day", cli.Millis(period.From) - 1, false},
+		{"first millisecond", cli.Millis(period.From), true},
+		{"first hour of winter time", cli.Millis(time.Date(2018, 10, 28, 2, 30, 0, 0, time.UTC)), true},
+		{"last millisecond", cli.Millis(period.To), true},
+		{"first millisecond of the next day", cli.Millis(period.To) + 1, false},
+	}
+
+	if ms := cli.Millis(period.To) % 1000; ms != 999 {
+		t.Errorf("period ends at millisecond %d, want 999", ms)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			at := cli.FromMillis(tt.ms)
+
+			if cli.Millis(at) != tt.ms {
+				t.Errorf("FromMillis(%d) = %s, which is %d", tt.ms, at.Format(periodLayout), cli.Millis(at))
+			}
+			if period.Contains(at) != tt.contains {
+				t.Errorf("period contains %s = %v, want %v", at.Format(periodLayout), !tt.contains, tt.contains)
+			}
+		})
+	}
+}
@@ -0,0 +1,200 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"sort"
+
+	"github.com/apognu/n26/cli"
+)
+
+const defaultProfile = "default"
+
+var (
+	profile = defaultProfile
+
+	profileName = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+)
+
+type profileRegistry struct {
+	Default  string   `json:"default"`
+	Profiles []string `json:"profiles"`
+}
+
+// UseProfile selects the profile whose credentials, configuration and local
+// data are used. An empty name selects the default profile.
+func UseProfile(name string) error {
+	registry, err := loadProfiles()
+	if err != nil {
+		return err
+	}
+
+	if name == "" {
+		name = registry.Default
+	}
+	if !registry.Has(name) {
+		return fmt.Errorf("profile '%s' does not exist, create it with 'n26 profiles add %s'", name, name)
+	}
+
+	profile = name
+	resetCredentialStore()
+	setSavedCredentials(nil)
+
+	return nil
+}
+
+func CurrentProfile() string {
+	return profile
+}
+
+func GetProfiles() (cli.ProfileList, error) {
+	registry, err := loadProfiles()
+	if err != nil {
+		return nil, err
+	}
+
+	list := cli.ProfileList{{Name: defaultProfile, Default: registry.Default == defaultProfile}}
+	for _, name := range registry.Profiles {
+		list = append(list, cli.Profile{Name: name, Default: registry.Default == name})
+	}
+
+	return list, nil
+}
+
+func AddProfile(name string) (cli.SimpleMessage, error) {
+	if !profileName.MatchString(name) {
+		return "", fmt.Errorf("profile names can only contain letters, digits, '-' and '_'")
+	}
+
+	registry, err := loadProfiles()
+	if err != nil {
+		return "", err
+	}
+	if registry.Has(name) {
+		return "", fmt.Errorf("profile '%s' already exists", name)
+	}
+
+	if err := os.MkdirAll(profileDir(name), 0700); err != nil {
+		return "", fmt.Errorf("could not create profile directory at '%s'", profileDir(name))
+	}
+
+	registry.Profiles = append(registry.Profiles, name)
+	sort.Strings(registry.Profiles)
+
+	if err := registry.Save(); err != nil {
+		return "", err
+	}
+
+	return (cli.SimpleMessage)(fmt.Sprintf("Profile '%s' has been created, use it with --profile %s.", name, name)), nil
+}
+
+func RemoveProfile(name string) (cli.SimpleMessage, error) {
+	if name == defaultProfile {
+		return "", fmt.Errorf("the default profile cannot be removed")
+	}
+
+	registry, err := loadProfiles()
+	if err != nil {
+		return "", err
+	}
+	if !registry.Has(name) {
+		return "", fmt.Errorf("profile '%s' does not exist", name)
+	}
+
+	// The credentials may be stored outside of the profile directory
+	current, conf := profile, config
+	if err := UseProfile(name); err == nil {
+		if _, err := LoadConfig(); err == nil {
+			credentialStore().Delete()
+		}
+	}
+	UseProfile(current)
+	config = conf
+
+	if err := os.RemoveAll(profileDir(name)); err != nil {
+		return "", fmt.Errorf("could not delete profile directory at '%s'", profileDir(name))
+	}
+
+	profiles := make([]string, 0, len(registry.Profiles))
+	for _, p := range registry.Profiles {
+		if p != name {
+			profiles = append(profiles, p)
+		}
+	}
+
+	registry.Profiles = profiles
+	if registry.Default == name {
+		registry.Default = defaultProfile
+	}
+
+	if err := registry.Save(); err != nil {
+		return "", err
+	}
+
+	return (cli.SimpleMessage)(fmt.Sprintf("Profile '%s' has been removed.", name)), nil
+}
+
+func SetDefaultProfile(name string) (cli.SimpleMessage, error) {
+	registry, err := loadProfiles()
+	if err != nil {
+		return "", err
+	}
+	if !registry.Has(name) {
+		return "", fmt.Errorf("profile '%s' does not exist", name)
+	}
+
+	registry.Default = name
+
+	if err := registry.Save(); err != nil {
+		return "", err
+	}
+
+	return (cli.SimpleMessage)(fmt.Sprintf("Profile '%s' is now used by default.", name)), nil
+}
+
+func loadProfiles() (*profileRegistry, error) {
+	registry := &profileRegistry{Default: defaultProfile}
+
+	data, err := ioutil.ReadFile(globalFile("profiles"))
+	if os.IsNotExist(err) {
+		return registry, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not read profiles at '%s'", globalFile("profiles"))
+	}
+
+	if err := json.Unmarshal(data, registry); err != nil {
+		return nil, fmt.Errorf("could not parse profiles at '%s'", globalFile("profiles"))
+	}
+
+	return registry, nil
+}
+
+func (registry *profileRegistry) Has(name string) bool {
+	if name == defaultProfile {
+		return true
+	}
+
+	for _, p := range registry.Profiles {
+		if p == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (registry *profileRegistry) Save() error {
+	data, err := json.Marshal(registry)
+	if err != nil {
+		return fmt.Errorf("could not marshal profiles")
+	}
+
+	if err := ioutil.WriteFile(globalFile("profiles"), data, 0600); err != nil {
+		return fmt.Errorf("could not write profiles to '%s'", globalFile("profiles"))
+	}
+
+	return nil
+}
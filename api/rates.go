@@ -0,0 +1,186 @@
+package api
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/apognu/n26/cli"
+)
+
+// RateTable holds historical exchange rates, expressed as units of each
+// currency for one unit of the base currency.
+type RateTable struct {
+	base  string
+	days  []string
+	rates map[string]map[string]float64
+}
+
+type ecbRates struct {
+	Days []struct {
+		Time  string `xml:"time,attr"`
+		Rates []struct {
+			Currency string  `xml:"currency,attr"`
+			Rate     float64 `xml:"rate,attr"`
+		} `xml:"Cube"`
+	} `xml:"Cube>Cube"`
+}
+
+// LoadRates reads a rate table from an ECB XML file (such as
+// eurofxref-hist.xml) or from a CSV file with a date column followed by one
+// column per currency (such as eurofxref-hist.csv). Rates are against the
+// euro, unless the header of the date column names another currency.
+func LoadRates(path string) (*RateTable, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read exchange rates file '%s'", path)
+	}
+
+	table := &RateTable{base: "EUR", rates: make(map[string]map[string]float64)}
+
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("<")) {
+		err = table.parseECB(data)
+	} else {
+		err = table.parseCSV(data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not parse exchange rates file '%s': %s", path, err)
+	}
+
+	if len(table.rates) == 0 {
+		return nil, fmt.Errorf("the exchange rates file '%s' does not contain any rate", path)
+	}
+
+	for day := range table.rates {
+		table.days = append(table.days, day)
+	}
+	sort.Strings(table.days)
+
+	return table, nil
+}
+
+func (table *RateTable) parseECB(data []byte) error {
+	var rates ecbRates
+	if err := xml.Unmarshal(data, &rates); err != nil {
+		return err
+	}
+
+	for _, day := range rates.Days {
+		if _, err := time.Parse("2006-01-02", day.Time); err != nil {
+			return fmt.Errorf("invalid date '%s'", day.Time)
+		}
+
+		for _, rate := range day.Rates {
+			table.add(day.Time, rate.Currency, rate.Rate)
+		}
+	}
+
+	return nil
+}
+
+func (table *RateTable) parseCSV(data []byte) error {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return err
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+
+	header := rows[0]
+	if isCurrency(header[0]) {
+		table.base = header[0]
+	}
+
+	for idx, row := range rows[1:] {
+		if _, err := time.Parse("2006-01-02", row[0]); err != nil {
+			return fmt.Errorf("line %d: invalid date '%s'", idx+2, row[0])
+		}
+
+		for col := 1; col < len(row) && col < len(header); col++ {
+			rate, err := strconv.ParseFloat(row[col], 64)
+			if err != nil || !isCurrency(header[col]) {
+				continue
+			}
+
+			table.add(row[0], header[col], rate)
+		}
+	}
+
+	return nil
+}
+
+func (table *RateTable) add(day, currency string, rate float64) {
+	if rate <= 0 {
+		return
+	}
+
+	if _, ok := table.rates[day]; !ok {
+		table.rates[day] = map[string]float64{table.base: 1}
+	}
+	table.rates[day][strings.ToUpper(currency)] = rate
+}
+
+// Rate returns the rate of a currency on the last day before the given date
+// for which it is known, to account for weekends and bank holidays.
+func (table *RateTable) Rate(currency string, at time.Time) (float64, error) {
+	day := at.In(cli.Location).Format("2006-01-02")
+
+	idx := sort.SearchStrings(table.days, day)
+	if idx < len(table.days) && table.days[idx] == day {
+		idx++
+	}
+
+	for idx--; idx >= 0; idx-- {
+		if rate, ok := table.rates[table.days[idx]][currency]; ok {
+			return rate, nil
+		}
+	}
+
+	return 0, fmt.Errorf("no exchange rate is known for %s on %s", currency, day)
+}
+
+// Convert converts an amount between two currencies at the rates of the
+// given date.
+func (table *RateTable) Convert(amount cli.Amount, from, to string, at time.Time) (cli.Amount, error) {
+	if strings.EqualFold(from, to) {
+		return amount, nil
+	}
+
+	if table.rates == nil {
+		return 0, fmt.Errorf("an exchange rates file must be configured to convert amounts from %s to %s", from, to)
+	}
+
+	fromRate, err := table.Rate(from, at)
+	if err != nil {
+		return 0, err
+	}
+	toRate, err := table.Rate(to, at)
+	if err != nil {
+		return 0, err
+	}
+
+	return amount.Exchange(fromRate, toRate).Round(to), nil
+}
+
+func isCurrency(code string) bool {
+	if len(code) != 3 {
+		return false
+	}
+	for _, c := range code {
+		if c < 'A' || c > 'Z' {
+			return false
+		}
+	}
+	return true
+}
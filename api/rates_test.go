@@ -0,0 +1,170 @@
+package api
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/apognu/n26/cli"
+)
+
+const (
+	ecbXML = `<?xml version="1.0" encoding="UTF-8"?>
+<gesmes:Envelope xmlns:gesmes="http://www.gesmes.org/xml/2002-08-01" xmlns="http://www.ecb.int/vocabulary/2002-08-01/eurofxref">
+	<gesmes:subject>Reference rates</gesmes:subject>
+	<Cube>
+		<Cube time="2018-11-09">
+			<Cube currency="USD" rate="1.1355"/>
+			<Cube currency="GBP" rate="0.8729"/>
+		</Cube>
+		<Cube time="2018-11-08">
+			<Cube currency="USD" rate="1.1428"/>
+			<Cube currency="GBP" rate="0.8739"/>
+		</Cube>
+	</Cube>
+</gesmes:Envelope>`
+
+	ecbCSV = "Date,USD,JPY,CYP,GBP,\n" +
+		"2018-11-09,1.1355,128.97,N/A,0.8729,\n" +
+		"2018-11-08,1.1428,129.86,N/A,0.8739,\n"
+)
+
+// rates writes an exchange rates file for the duration of a test.
+func rates(t *testing.T, name, data string) string {
+	path := filepath.Join(t.TempDir(), name)
+	if err := ioutil.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func day(s string) time.Time {
+	at, _ := time.ParseInLocation("2006-01-02 15:04", s, cli.Location)
+	return at
+}
+
+func TestLoadRates(t *testing.T) {
+	tests := []struct {
+		name     string
+		file     string
+		data     string
+		currency string
+		at       string
+		rate     float64
+		err      bool
+	}{
+		{name: "xml", file: "rates.xml", data: ecbXML, currency: "USD", at: "2018-11-09 12:00", rate: 1.1355},
+		{name: "xml base", file: "rates.xml", data: ecbXML, currency: "EUR", at: "2018-11-09 12:00", rate: 1},
+		{name: "csv", file: "rates.csv", data: ecbCSV, currency: "JPY", at: "2018-11-08 12:00", rate: 129.86},
+		{name: "csv not available", file: "rates.csv", data: ecbCSV, currency: "CYP", at: "2018-11-09 12:00", err: true},
+		{name: "csv trailing column", file: "rates.csv", data: ecbCSV, currency: "", at: "2018-11-09 12:00", err: true},
+		{name: "csv base", file: "rates.csv", data: "USD,EUR\n2018-11-09,0.8807\n", currency: "USD", at: "2018-11-09 12:00", rate: 1},
+		{name: "weekend", file: "rates.xml", data: ecbXML, currency: "USD", at: "2018-11-11 23:00", rate: 1.1355},
+		{name: "start of the day", file: "rates.xml", data: ecbXML, currency: "GBP", at: "2018-11-09 00:00", rate: 0.8729},
+		{name: "before the first day", file: "rates.xml", data: ecbXML, currency: "USD", at: "2018-11-07 12:00", err: true},
+		{name: "unknown currency", file: "rates.csv", data: ecbCSV, currency: "CHF", at: "2018-11-09 12:00", err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			table, err := LoadRates(rates(t, tt.file, tt.data))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rate, err := table.Rate(tt.currency, day(tt.at))
+
+			if tt.err {
+				if err == nil {
+					t.Errorf("Rate(%q, %s) = %v, want an error", tt.currency, tt.at, rate)
+				}
+				return
+			}
+
+			if err != nil || rate != tt.rate {
+				t.Errorf("Rate(%q, %s) = (%v, %v), want %v", tt.currency, tt.at, rate, err, tt.rate)
+			}
+		})
+	}
+}
+
+func TestLoadRatesErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		data string
+	}{
+		{"invalid xml date", "rates.xml", `<Envelope><Cube><Cube time="09/11/2018"><Cube currency="USD" rate="1.1355"/></Cube></Cube></Envelope>`},
+		{"invalid csv date", "rates.csv", "Date,USD\n09/11/2018,1.1355\n"},
+		{"no rate", "rates.csv", "Date,USD\n2018-11-09,N/A\n"},
+		{"empty", "rates.csv", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := LoadRates(rates(t, tt.file, tt.data)); err == nil {
+				t.Error("LoadRates() accepted an invalid file")
+			}
+		})
+	}
+}
+
+func TestConvert(t *testing.T) {
+	table, err := LoadRates(rates(t, "rates.csv", ecbCSV))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		table     *RateTable
+		amount    cli.Amount
+		from, to  string
+		converted cli.Amount
+		err       bool
+	}{
+		{name: "from the base", table: table, amount: 100000, from: "EUR", to: "USD", converted: 113550},
+		{name: "to the base", table: table, amount: 113550, from: "USD", to: "EUR", converted: 100000},
+		{name: "cross rate", table: table, amount: 100000, from: "USD", to: "GBP", converted: 76870},
+		{name: "minor unit", table: table, amount: 100000, from: "EUR", to: "JPY", converted: 12897000},
+		{name: "same currency", table: table, amount: 12345, from: "CHF", to: "CHF", converted: 12345},
+		{name: "same currency without rates", table: new(RateTable), amount: 12340, from: "EUR", to: "eur", converted: 12340},
+		{name: "without rates", table: new(RateTable), amount: 12340, from: "EUR", to: "USD", err: true},
+		{name: "unknown currency", table: table, amount: 12340, from: "EUR", to: "CHF", err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			converted, err := tt.table.Convert(tt.amount, tt.from, tt.to, day("2018-11-10 12:00"))
+
+			if tt.err {
+				if err == nil {
+					t.Errorf("Convert(%s %s, %s) = %s, want an error", tt.amount, tt.from, tt.to, converted)
+				}
+				return
+			}
+
+			if err != nil || converted != tt.converted {
+				t.Errorf("Convert(%s %s, %s) = (%s, %v), want %s", tt.amount, tt.from, tt.to, converted, err, tt.converted)
+			}
+		})
+	}
+}
+
+func TestReportWithoutRates(t *testing.T) {
+	defer func(rates string) {
+		config.Rates = rates
+	}(config.Rates)
+	config.Rates = ""
+
+	balance := &cli.Balance{AvailableBalance: 12340, UsageBalance: 10000, Currency: "EUR"}
+
+	if _, err := Report(balance, "eur"); err != nil || balance.AvailableBalance != 12340 || balance.Currency != "EUR" {
+		t.Errorf("Report(EUR) = (%+v, %v), want the balance unchanged", balance, err)
+	}
+
+	if _, err := Report(balance, "USD"); err == nil {
+		t.Error("Report(USD) converted the balance without exchange rates")
+	}
+}
@@ -0,0 +1,149 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/apognu/n26/cli"
+)
+
+// Report converts the amounts of balances, spaces, transactions and
+// statistics to another currency, using the exchange rates of the file
+// configured in the configuration file. Transactions are converted at the
+// rate of the day they were made, everything else at the latest known rate.
+func Report(cmd cli.Printable, currency string) (cli.Printable, error) {
+	switch cmd.(type) {
+	case *cli.Balance, *cli.Spaces, cli.PastTransactionList, *cli.Statistics:
+	default:
+		return nil, fmt.Errorf("--report-currency is not supported for this command")
+	}
+
+	currency = strings.ToUpper(currency)
+
+	// Without a rates file, only amounts already in the requested currency
+	// can be reported.
+	table := new(RateTable)
+	if config.Rates != "" {
+		var err error
+		if table, err = LoadRates(config.Rates); err != nil {
+			return nil, err
+		}
+	}
+
+	now := time.Now()
+
+	switch cmd := cmd.(type) {
+	case *cli.Balance:
+		if err := convert(table, &cmd.AvailableBalance, cmd.Currency, currency, now); err != nil {
+			return nil, err
+		}
+		if err := convert(table, &cmd.UsageBalance, cmd.Currency, currency, now); err != nil {
+			return nil, err
+		}
+
+		cmd.Currency = currency
+
+	case *cli.Spaces:
+		for _, space := range cmd.Spaces {
+			if space.Primary {
+				if err := convert(table, &cmd.Balance, space.Balance.Currency, currency, now); err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		for idx := range cmd.Spaces {
+			space := &cmd.Spaces[idx]
+
+			if err := convert(table, &space.Goal.Amount, space.Balance.Currency, currency, now); err != nil {
+				return nil, err
+			}
+			if err := convert(table, &space.Balance.AvailableBalance, space.Balance.Currency, currency, now); err != nil {
+				return nil, err
+			}
+
+			space.Balance.Currency = currency
+		}
+
+	case cli.PastTransactionList:
+		if err := reportTransactions(table, cmd, currency); err != nil {
+			return nil, err
+		}
+
+	case *cli.Statistics:
+		if err := reportStatistics(table, cmd, currency); err != nil {
+			return nil, err
+		}
+	}
+
+	return cmd, nil
+}
+
+func convert(table *RateTable, amount *cli.Amount, from, to string, at time.Time) error {
+	converted, err := table.Convert(*amount, from, to, at)
+	if err != nil {
+		return err
+	}
+
+	*amount = converted
+	return nil
+}
+
+func reportTransactions(table *RateTable, transactions cli.PastTransactionList, currency string) error {
+	for idx := range transactions {
+		trx := &transactions[idx]
+
+		amount, err := table.Convert(trx.Amount, trx.Currency, currency, cli.FromMillis(trx.Date))
+		if err != nil {
+			return err
+		}
+
+		trx.Report = &cli.Money{Amount: amount, Currency: currency}
+	}
+
+	return nil
+}
+
+// reportStatistics computes the statistics again from the transactions of
+// the period, since only those can be converted at the rate of their day.
+func reportStatistics(table *RateTable, stats *cli.Statistics, currency string) error {
+	if stats.Transactions == nil {
+		return errTooManyTransactions
+	}
+
+	if err := reportTransactions(table, stats.Transactions, currency); err != nil {
+		return err
+	}
+
+	stats.Currency = currency
+	stats.TotalIncome, stats.TotalExpense = 0, 0
+	stats.Movements = make([]cli.CategoryMovement, 0)
+
+	indexes := make(map[string]int)
+
+	for _, trx := range stats.Transactions {
+		if trx.Scheme == "SPACES" {
+			continue
+		}
+
+		idx, ok := indexes[trx.Category]
+		if !ok {
+			idx = len(stats.Movements)
+			indexes[trx.Category] = idx
+			stats.Movements = append(stats.Movements, cli.CategoryMovement{Category: trx.Category})
+		}
+
+		if trx.Report.Amount < 0 {
+			stats.Movements[idx].Expense += trx.Report.Amount.Abs()
+			stats.TotalExpense += trx.Report.Amount.Abs()
+		} else {
+			stats.Movements[idx].Income += trx.Report.Amount
+			stats.TotalIncome += trx.Report.Amount
+		}
+	}
+
+	stats.Currencies, stats.FXFees = currencyTotals(stats.Transactions)
+
+	return nil
+}
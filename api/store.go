@@ -0,0 +1,393 @@
+package api
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+
+	"github.com/apognu/n26/cli"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	storeSecretService = "secret-service"
+	storePass          = "pass"
+	storeEncryptedFile = "encrypted-file"
+	storeFile          = "file"
+)
+
+// CredentialStore persists the serialized OAuth credentials.
+type CredentialStore interface {
+	Load() ([]byte, error)
+	Save(data []byte) error
+	Delete() error
+}
+
+var (
+	store     CredentialStore
+	storeName string
+	storeMu   sync.Mutex
+)
+
+// SetCredentialStore overrides the store configured in the configuration
+// file, for use as a library.
+func SetCredentialStore(s CredentialStore) {
+	storeMu.Lock()
+	defer storeMu.Unlock()
+
+	store, storeName = s, "custom"
+}
+
+func resetCredentialStore() {
+	storeMu.Lock()
+	defer storeMu.Unlock()
+
+	store = nil
+}
+
+func credentialStore() CredentialStore {
+	storeMu.Lock()
+	defer storeMu.Unlock()
+
+	if store != nil {
+		return store
+	}
+
+	conf := config.Credentials
+
+	name := conf.Store
+	if name == "" {
+		name = storeEncryptedFile
+		if _, err := exec.LookPath("secret-tool"); err == nil {
+			name = storeSecretService
+		}
+	}
+
+	storeName = name
+
+	switch name {
+	case storeSecretService:
+		store = secretServiceStore{profile: profile}
+	case storePass:
+		entry := conf.PassEntry
+		if entry == "" {
+			entry = "n26/credentials"
+			if profile != defaultProfile {
+				entry = fmt.Sprintf("n26/%s/credentials", profile)
+			}
+		}
+		store = passStore{entry: entry}
+	case storeEncryptedFile:
+		store = &encryptedFileStore{path: ConfigPath()}
+	case storeFile:
+		store = fileStore{path: ConfigPath()}
+	default:
+		cli.Fatal(fmt.Errorf("unknown credential store '%s'", name))
+	}
+
+	return store
+}
+
+type fileStore struct {
+	path string
+}
+
+func (s fileStore) Load() ([]byte, error) {
+	return ioutil.ReadFile(s.path)
+}
+
+func (s fileStore) Save(data []byte) error {
+	if err := writeFileAtomic(s.path, data); err != nil {
+		return fmt.Errorf("could not write credentials file to '%s'", s.path)
+	}
+	return nil
+}
+
+func (s fileStore) Delete() error {
+	if err := os.Remove(s.path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("could not delete credentials file at '%s'", s.path)
+	}
+	return nil
+}
+
+type encryptedFile struct {
+	KDF   string `json:"kdf"`
+	Salt  []byte `json:"salt"`
+	Nonce []byte `json:"nonce"`
+	Data  []byte `json:"data"`
+}
+
+// passphraseAttempts is the number of times a mistyped passphrase is asked
+// for before giving up.
+const passphraseAttempts = 3
+
+// errWrongPassphrase is returned instead of logging in again, so that a
+// mistyped passphrase is never used to encrypt the new credentials.
+var errWrongPassphrase = fmt.Errorf("could not decrypt credentials, the passphrase may be wrong")
+
+type encryptedFileStore struct {
+	path string
+
+	mu   sync.Mutex
+	salt []byte
+	key  []byte
+}
+
+func (s *encryptedFileStore) Load() ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := ioutil.ReadFile(s.path)
+	if err != nil {
+		return nil, err
+	}
+
+	file := new(encryptedFile)
+	if err := json.Unmarshal(data, file); err != nil || file.KDF != "scrypt" {
+		return nil, fmt.Errorf("credentials file at '%s' is not encrypted", s.path)
+	}
+
+	for attempt := 1; ; attempt++ {
+		if !bytes.Equal(s.salt, file.Salt) {
+			if err := s.deriveKey(file.Salt, false); err != nil {
+				return nil, err
+			}
+		}
+
+		aead, err := s.cipher()
+		if err != nil {
+			return nil, err
+		}
+
+		plain, err := aead.Open(nil, file.Nonce, file.Data, nil)
+		if err == nil {
+			saveSessionKey(s.salt, s.key)
+
+			return plain, nil
+		}
+
+		s.salt, s.key = nil, nil
+		clearSessionKey()
+
+		if !forgetPassphrase() || attempt == passphraseAttempts {
+			return nil, errWrongPassphrase
+		}
+
+		cli.Warn(fmt.Errorf("the passphrase is wrong, please try again"))
+	}
+}
+
+func (s *encryptedFileStore) Save(data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.key == nil {
+		salt := make([]byte, 16)
+		if _, err := rand.Read(salt); err != nil {
+			return fmt.Errorf("could not generate salt")
+		}
+		if err := s.deriveKey(salt, true); err != nil {
+			return err
+		}
+	}
+
+	aead, err := s.cipher()
+	if err != nil {
+		return err
+	}
+
+	file := encryptedFile{KDF: "scrypt", Salt: s.salt, Nonce: make([]byte, aead.NonceSize())}
+	if _, err := rand.Read(file.Nonce); err != nil {
+		return fmt.Errorf("could not generate nonce")
+	}
+	file.Data = aead.Seal(nil, file.Nonce, data, nil)
+
+	out, err := json.Marshal(file)
+	if err != nil {
+		return fmt.Errorf("could not marshal credentials")
+	}
+
+	saveSessionKey(s.salt, s.key)
+
+	return fileStore{path: s.path}.Save(out)
+}
+
+func (s *encryptedFileStore) Delete() error {
+	clearSessionKey()
+
+	return fileStore{path: s.path}.Delete()
+}
+
+func (s *encryptedFileStore) deriveKey(salt []byte, confirm bool) error {
+	if key := sessionKey(salt); key != nil {
+		s.salt, s.key = salt, key
+		return nil
+	}
+
+	passphrase, err := readPassphrase(confirm)
+	if err != nil {
+		return err
+	}
+
+	key, err := scrypt.Key([]byte(passphrase), salt, 1<<15, 8, 1, 32)
+	if err != nil {
+		return fmt.Errorf("could not derive encryption key")
+	}
+
+	s.salt, s.key = salt, key
+
+	return nil
+}
+
+func (s *encryptedFileStore) cipher() (cipher.AEAD, error) {
+	block, err := aes.NewCipher(s.key)
+	if err != nil {
+		return nil, fmt.Errorf("could not initialize encryption")
+	}
+	return cipher.NewGCM(block)
+}
+
+type secretServiceStore struct {
+	profile string
+}
+
+func (s secretServiceStore) Load() ([]byte, error) {
+	data, err := lookup("secret-tool", "lookup", "service", "n26", "profile", s.profile)
+	if err == nil || s.profile != defaultProfile {
+		return data, err
+	}
+
+	if legacy, ok := s.migrate(); ok {
+		return legacy, nil
+	}
+
+	return nil, err
+}
+
+// migrate moves the credentials stored before profiles existed, in an item
+// without a profile attribute, to the default profile.
+func (s secretServiceStore) migrate() ([]byte, bool) {
+	out, err := run(nil, "secret-tool", "search", "--all", "--unlock", "service", "n26")
+	if err != nil {
+		return nil, false
+	}
+
+	items := parseSecretItems(out)
+
+	var legacy []byte
+	for _, item := range items {
+		if _, ok := item["attribute.profile"]; !ok && item["secret"] != "" {
+			legacy = []byte(item["secret"])
+		}
+	}
+	if legacy == nil {
+		return nil, false
+	}
+
+	// Items can only be cleared by attributes, and those of the legacy item
+	// also match the items of every profile, so it is only removed when it
+	// is the only one.
+	removed := false
+	if len(items) == 1 {
+		_, err := run(nil, "secret-tool", "clear", "service", "n26")
+		removed = err == nil
+	}
+
+	if err := s.Save(legacy); err != nil {
+		cli.Warn(fmt.Errorf("could not move the credentials of your keyring to the default profile: %s", err))
+		return legacy, true
+	}
+
+	if removed {
+		cli.Warn(fmt.Errorf("the credentials of your keyring have been moved to the default profile"))
+	} else {
+		cli.Warn(fmt.Errorf("the credentials of your keyring have been copied to the default profile, the previous item labelled 'N26 credentials' can be deleted"))
+	}
+
+	return legacy, true
+}
+
+// parseSecretItems parses the output of 'secret-tool search' into the
+// properties and attributes of each item.
+func parseSecretItems(out []byte) []map[string]string {
+	items := make([]map[string]string, 0)
+
+	for _, line := range strings.Split(string(out), "\n") {
+		if strings.HasPrefix(line, "[") {
+			items = append(items, make(map[string]string))
+			continue
+		}
+
+		parts := strings.SplitN(line, " = ", 2)
+		if len(parts) != 2 || len(items) == 0 {
+			continue
+		}
+
+		items[len(items)-1][parts[0]] = parts[1]
+	}
+
+	return items
+}
+
+func (s secretServiceStore) Save(data []byte) error {
+	_, err := run(data, "secret-tool", "store", fmt.Sprintf("--label=N26 credentials (%s)", s.profile), "service", "n26", "profile", s.profile)
+	return err
+}
+
+func (s secretServiceStore) Delete() error {
+	_, err := run(nil, "secret-tool", "clear", "service", "n26", "profile", s.profile)
+	return err
+}
+
+type passStore struct {
+	entry string
+}
+
+func (s passStore) Load() ([]byte, error) {
+	return lookup("pass", "show", s.entry)
+}
+
+func (s passStore) Save(data []byte) error {
+	_, err := run(data, "pass", "insert", "--multiline", "--force", s.entry)
+	return err
+}
+
+func (s passStore) Delete() error {
+	_, err := run(nil, "pass", "rm", "--force", s.entry)
+	return err
+}
+
+func run(stdin []byte, name string, args ...string) ([]byte, error) {
+	cmd := exec.Command(name, args...)
+	cmd.Stdin = bytes.NewReader(stdin)
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("%s failed: %s", name, strings.TrimSpace(stderr.String()))
+	}
+
+	return out, nil
+}
+
+func lookup(name string, args ...string) ([]byte, error) {
+	out, err := run(nil, name, args...)
+	if err != nil {
+		return nil, err
+	}
+	if len(bytes.TrimSpace(out)) == 0 {
+		return nil, fmt.Errorf("%s returned no credentials", name)
+	}
+
+	return out, nil
+}
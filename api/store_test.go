@@ -0,0 +1,56 @@
+package api
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestEncryptedFileStorePassphrase(t *testing.T) {
+	t.Setenv("XDG_RUNTIME_DIR", "")
+	t.Setenv("N26_PASSPHRASE", "")
+
+	defer func(p string, provided bool) {
+		passphrase, passphraseProvided = p, provided
+	}(passphrase, passphraseProvided)
+
+	path := filepath.Join(t.TempDir(), "auth")
+	credentials := []byte(`{"refresh_token":"refresh"}`)
+
+	SetPassphrase("right")
+	if err := (&encryptedFileStore{path: path}).Save(credentials); err != nil {
+		t.Fatal(err)
+	}
+
+	SetPassphrase("wrong")
+
+	s := &encryptedFileStore{path: path}
+	if _, err := s.Load(); err != errWrongPassphrase {
+		t.Fatalf("Load() error = %v, want %v", err, errWrongPassphrase)
+	}
+	if s.key != nil || s.salt != nil {
+		t.Error("the key derived from a wrong passphrase was kept")
+	}
+
+	SetPassphrase("right")
+
+	data, err := s.Load()
+	if err != nil || string(data) != string(credentials) {
+		t.Errorf("Load() = (%s, %v), want %s", data, err, credentials)
+	}
+}
+
+func TestForgetPassphrase(t *testing.T) {
+	defer func(p string, provided bool) {
+		passphrase, passphraseProvided = p, provided
+	}(passphrase, passphraseProvided)
+
+	SetPassphrase("provided")
+	if forgetPassphrase() || passphrase != "provided" {
+		t.Error("a provided passphrase was forgotten, it cannot be asked for again")
+	}
+
+	passphrase, passphraseProvided = "typed", false
+	if !forgetPassphrase() || passphrase != "" {
+		t.Error("a typed passphrase was kept after failing to decrypt the credentials")
+	}
+}
@@ -0,0 +1,261 @@
+package api
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/apognu/n26/cli"
+)
+
+type cadence struct {
+	name      string
+	days      float64
+	tolerance float64
+	months    int
+	perYear   int
+	minimum   int
+}
+
+// cadences are the intervals recurring payments are looked for at, with how
+// many days a payment may be early or late and how many payments are needed
+// to consider them recurring.
+var cadences = []cadence{
+	{name: "weekly", days: 7, tolerance: 1, perYear: 52, minimum: 3},
+	{name: "monthly", days: 30.44, tolerance: 4, months: 1, perYear: 12, minimum: 3},
+	{name: "quarterly", days: 91.31, tolerance: 8, months: 3, perYear: 4, minimum: 2},
+	{name: "yearly", days: 365.25, tolerance: 12, months: 12, perYear: 1, minimum: 2},
+}
+
+// amountTolerance is how far from their usual amount the payments of a
+// subscription can be, as a fraction of that amount.
+const amountTolerance = 0.3
+
+func getCadence(name string) (cadence, bool) {
+	for _, c := range cadences {
+		if c.name == name {
+			return c, true
+		}
+	}
+	return cadence{}, false
+}
+
+// next returns when the payment following the one at t is expected. Monthly
+// payments made at the end of a month are expected at the end of the next
+// month, even if it is shorter.
+func (c cadence) next(t time.Time) time.Time {
+	if c.months == 0 {
+		return t.AddDate(0, 0, int(c.days))
+	}
+
+	first := time.Date(t.Year(), t.Month()+time.Month(c.months), 1, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+
+	day := t.Day()
+	if last := first.AddDate(0, 1, -1).Day(); day > last {
+		day = last
+	}
+
+	return first.AddDate(0, 0, day-1)
+}
+
+// GetSubscriptions looks for recurring payments in the transactions of the
+// last months: payments to the same partner or merchant, of a similar
+// amount, at a regular interval.
+func (cl *N26Client) GetSubscriptions(meta *cli.Metadata, months int) (cli.SubscriptionList, error) {
+	if months < 1 {
+		return nil, fmt.Errorf("the history must cover at least one month")
+	}
+
+	now := time.Now().In(cli.Location)
+	period, err := parsePeriod(fmt.Sprintf("-%dm", months), "", now)
+	if err != nil {
+		return nil, err
+	}
+
+	transactions, err := cl.getAllTransactions(period)
+	if err != nil {
+		return nil, err
+	}
+
+	return detectRecurring(transactions, now, false), nil
+}
+
+// detectRecurring looks for recurring incoming or outgoing payments.
+func detectRecurring(transactions cli.PastTransactionList, now time.Time, incoming bool) cli.SubscriptionList {
+	groups := make(map[string]cli.PastTransactionList)
+
+	for _, trx := range transactions {
+		if trx.Amount == 0 || (trx.Amount > 0) != incoming || trx.Scheme == "SPACES" {
+			continue
+		}
+
+		key := strings.ToLower(strings.TrimSpace(partnerName(trx)))
+		if key == "" {
+			continue
+		}
+
+		groups[key] = append(groups[key], trx)
+	}
+
+	subscriptions := make(cli.SubscriptionList, 0)
+
+	for _, group := range groups {
+		if sub, ok := detectSubscription(group, now); ok {
+			subscriptions = append(subscriptions, sub)
+		}
+	}
+
+	sort.Slice(subscriptions, func(i, j int) bool {
+		return subscriptions[i].Yearly > subscriptions[j].Yearly
+	})
+
+	return subscriptions
+}
+
+func detectSubscription(group cli.PastTransactionList, now time.Time) (cli.Subscription, bool) {
+	var sub cli.Subscription
+
+	sort.Slice(group, func(i, j int) bool { return group[i].Date < group[j].Date })
+
+	payments := subscriptionPayments(group)
+	if len(payments) < 2 {
+		return sub, false
+	}
+
+	intervals := make([]float64, len(payments)-1)
+	for idx := range intervals {
+		intervals[idx] = cli.FromMillis(payments[idx+1].Date).Sub(cli.FromMillis(payments[idx].Date)).Hours() / 24
+	}
+
+	sorted := append([]float64(nil), intervals...)
+	sort.Float64s(sorted)
+	typical := sorted[len(sorted)/2]
+
+	for _, c := range cadences {
+		if len(payments) < c.minimum || math.Abs(typical-c.days) > c.tolerance {
+			continue
+		}
+
+		// Intervals spanning several periods mean payments were missed, any
+		// other irregular interval means these are not a subscription.
+		missed := 0
+		for _, interval := range intervals {
+			periods := math.Max(1, math.Floor(interval/c.days+0.5))
+			if math.Abs(interval-periods*c.days) > c.tolerance*periods {
+				return sub, false
+			}
+			missed += int(periods) - 1
+		}
+
+		// The last payment of the group, alone at its price, is the first
+		// one at a new price if it came when the next payment was expected.
+		if latest := group[len(group)-1]; latest.Date > payments[len(payments)-1].Date {
+			interval := cli.FromMillis(latest.Date).Sub(cli.FromMillis(payments[len(payments)-1].Date)).Hours() / 24
+			if math.Abs(interval-c.days) <= c.tolerance {
+				payments = append(payments, latest)
+			}
+		}
+
+		last := payments[len(payments)-1]
+
+		sub.Name = partnerName(last)
+		sub.Cadence = c.name
+		sub.Occurrences = len(payments)
+		sub.Amount = last.Amount.Abs()
+
+		// The previous amount is the last one that differs, so a price
+		// change remains visible after the following payments.
+		sub.PreviousAmount = sub.Amount
+		for idx := len(payments) - 2; idx >= 0 && sub.PreviousAmount == sub.Amount; idx-- {
+			sub.PreviousAmount = payments[idx].Amount.Abs()
+		}
+
+		sub.Currency = last.Currency
+		sub.Last = cli.FromMillis(last.Date)
+		sub.Next = c.next(sub.Last)
+		sub.Yearly = sub.Amount * cli.Amount(c.perYear)
+		sub.Monthly = (sub.Yearly / 12).Round(sub.Currency)
+
+		for next := sub.Next; now.Sub(next).Hours()/24 > c.tolerance; next = c.next(next) {
+			sub.Next = c.next(next)
+			missed++
+		}
+		sub.Missed = missed
+
+		return sub, true
+	}
+
+	return sub, false
+}
+
+// subscriptionPayments separates the payments of a subscription from one-off
+// purchases from the same merchant. Payments of similar amounts form price
+// levels: the level with the most payments holds the usual price, the levels
+// of at least two payments entirely before or after it are its previous and
+// following prices, and any other payment is a one-off purchase.
+func subscriptionPayments(group cli.PastTransactionList) cli.PastTransactionList {
+	levels := priceLevels(group)
+
+	var series cli.PastTransactionList
+	for _, level := range levels {
+		if len(level) > len(series) || (len(level) == len(series) && level[len(level)-1].Date > series[len(series)-1].Date) {
+			series = level
+		}
+	}
+
+	for {
+		var previous, following cli.PastTransactionList
+
+		for _, level := range levels {
+			if len(level) < 2 {
+				continue
+			}
+			if level[len(level)-1].Date < series[0].Date && (previous == nil || level[len(level)-1].Date > previous[len(previous)-1].Date) {
+				previous = level
+			}
+			if level[0].Date > series[len(series)-1].Date && (following == nil || level[0].Date < following[0].Date) {
+				following = level
+			}
+		}
+
+		if previous == nil && following == nil {
+			return series
+		}
+
+		extended := make(cli.PastTransactionList, 0, len(previous)+len(series)+len(following))
+		extended = append(extended, previous...)
+		extended = append(extended, series...)
+		series = append(extended, following...)
+	}
+}
+
+// priceLevels groups the payments whose amounts are within amountTolerance
+// of the smallest amount of their group, keeping them in chronological
+// order.
+func priceLevels(group cli.PastTransactionList) []cli.PastTransactionList {
+	sorted := append(cli.PastTransactionList(nil), group...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Amount.Abs() < sorted[j].Amount.Abs() })
+
+	levels := make([]cli.PastTransactionList, 0)
+	for _, trx := range sorted {
+		if len(levels) == 0 || trx.Amount.Abs().Ratio(levels[len(levels)-1][0].Amount.Abs())-1 > amountTolerance {
+			levels = append(levels, nil)
+		}
+		levels[len(levels)-1] = append(levels[len(levels)-1], trx)
+	}
+
+	for _, level := range levels {
+		sort.Slice(level, func(i, j int) bool { return level[i].Date < level[j].Date })
+	}
+
+	return levels
+}
+
+func partnerName(trx cli.PastTransaction) string {
+	if trx.Partner != "" {
+		return trx.Partner
+	}
+	return trx.MerchantName
+}
@@ -0,0 +1,145 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/apognu/n26/cli"
+)
+
+func payments(amount cli.Amount, dates ...string) cli.PastTransactionList {
+	list := make(cli.PastTransactionList, len(dates))
+	for idx, date := range dates {
+		t, err := time.ParseInLocation("2006-01-02", date, cli.Location)
+		if err != nil {
+			panic(err)
+		}
+
+		list[idx] = cli.PastTransaction{MerchantName: "Streaming", Amount: -amount, Currency: "EUR", Date: cli.Millis(t)}
+	}
+	return list
+}
+
+func join(lists ...cli.PastTransactionList) cli.PastTransactionList {
+	all := make(cli.PastTransactionList, 0)
+	for _, list := range lists {
+		all = append(all, list...)
+	}
+	return all
+}
+
+func TestDetectSubscription(t *testing.T) {
+	defer func(loc *time.Location) { cli.Location = loc }(cli.Location)
+	cli.Location = time.UTC
+
+	tests := []struct {
+		name        string
+		group       cli.PastTransactionList
+		now         string
+		ok          bool
+		cadence     string
+		occurrences int
+		amount      cli.Amount
+		previous    cli.Amount
+		missed      int
+		next        string
+	}{
+		{
+			name:  "monthly across short months",
+			group: payments(9990, "2018-01-31", "2018-02-28", "2018-03-31", "2018-04-30", "2018-05-31"),
+			now:   "2018-06-10", ok: true, cadence: "monthly", occurrences: 5, amount: 9990, previous: 9990, next: "2018-06-30",
+		},
+		{
+			name:  "missed month",
+			group: payments(9990, "2018-01-15", "2018-02-15", "2018-04-15", "2018-05-15"),
+			now:   "2018-05-20", ok: true, cadence: "monthly", occurrences: 4, amount: 9990, previous: 9990, missed: 1, next: "2018-06-15",
+		},
+		{
+			name:  "missed last month",
+			group: payments(9990, "2018-01-15", "2018-02-15", "2018-03-15", "2018-04-15"),
+			now:   "2018-06-01", ok: true, cadence: "monthly", occurrences: 4, amount: 9990, previous: 9990, missed: 1, next: "2018-06-15",
+		},
+		{
+			name:  "payment late within tolerance",
+			group: payments(9990, "2018-01-15", "2018-02-15", "2018-03-15", "2018-04-15"),
+			now:   "2018-05-18", ok: true, cadence: "monthly", occurrences: 4, amount: 9990, previous: 9990, next: "2018-05-15",
+		},
+		{
+			name:  "price increase below tolerance",
+			group: join(payments(10000, "2018-01-15", "2018-02-15", "2018-03-15"), payments(12000, "2018-04-15", "2018-05-15")),
+			now:   "2018-05-20", ok: true, cadence: "monthly", occurrences: 5, amount: 12000, previous: 10000, next: "2018-06-15",
+		},
+		{
+			name:  "price increase above tolerance",
+			group: join(payments(10000, "2018-01-15", "2018-02-15", "2018-03-15", "2018-04-15"), payments(15000, "2018-05-15", "2018-06-15")),
+			now:   "2018-06-20", ok: true, cadence: "monthly", occurrences: 6, amount: 15000, previous: 10000, next: "2018-07-15",
+		},
+		{
+			name:  "first payment at a new price",
+			group: join(payments(10000, "2018-01-15", "2018-02-15", "2018-03-15", "2018-04-15"), payments(15000, "2018-05-15")),
+			now:   "2018-05-20", ok: true, cadence: "monthly", occurrences: 5, amount: 15000, previous: 10000, next: "2018-06-15",
+		},
+		{
+			name:  "one-off purchase between payments",
+			group: join(payments(10000, "2018-01-15", "2018-02-15", "2018-03-15", "2018-04-15"), payments(60000, "2018-03-02")),
+			now:   "2018-04-20", ok: true, cadence: "monthly", occurrences: 4, amount: 10000, previous: 10000, next: "2018-05-15",
+		},
+		{
+			name:  "one-off purchase after the last payment",
+			group: join(payments(10000, "2018-01-15", "2018-02-15", "2018-03-15", "2018-04-15"), payments(60000, "2018-04-25")),
+			now:   "2018-04-26", ok: true, cadence: "monthly", occurrences: 4, amount: 10000, previous: 10000, next: "2018-05-15",
+		},
+		{
+			name:  "weekly",
+			group: payments(4500, "2018-03-05", "2018-03-12", "2018-03-19", "2018-03-26", "2018-04-02"),
+			now:   "2018-04-03", ok: true, cadence: "weekly", occurrences: 5, amount: 4500, previous: 4500, next: "2018-04-09",
+		},
+		{
+			name:  "yearly rather than quarterly with missed payments",
+			group: payments(49000, "2016-03-01", "2017-03-01", "2018-03-01"),
+			now:   "2018-06-01", ok: true, cadence: "yearly", occurrences: 3, amount: 49000, previous: 49000, next: "2019-03-01",
+		},
+		{
+			name:  "quarterly with a missed quarter",
+			group: payments(30000, "2017-01-10", "2017-04-10", "2017-07-10", "2018-01-10"),
+			now:   "2018-02-01", ok: true, cadence: "quarterly", occurrences: 4, amount: 30000, previous: 30000, missed: 1, next: "2018-04-10",
+		},
+		{
+			name:  "too few weekly payments",
+			group: payments(4500, "2018-03-05", "2018-03-12"),
+			now:   "2018-03-13",
+		},
+		{
+			name:  "irregular payments",
+			group: payments(2000, "2018-01-01", "2018-01-20", "2018-03-05", "2018-03-09"),
+			now:   "2018-03-10",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now, _ := time.ParseInLocation("2006-01-02", tt.now, cli.Location)
+
+			sub, ok := detectSubscription(tt.group, now)
+			if ok != tt.ok {
+				t.Fatalf("detectSubscription() detected = %v, want %v (%+v)", ok, tt.ok, sub)
+			}
+			if !ok {
+				return
+			}
+
+			if sub.Cadence != tt.cadence || sub.Occurrences != tt.occurrences || sub.Missed != tt.missed {
+				t.Errorf("detectSubscription() = %s, %d payments, %d missed, want %s, %d payments, %d missed", sub.Cadence, sub.Occurrences, sub.Missed, tt.cadence, tt.occurrences, tt.missed)
+			}
+			if sub.Amount != tt.amount || sub.PreviousAmount != tt.previous {
+				t.Errorf("detectSubscription() amount = %s after %s, want %s after %s", sub.Amount, sub.PreviousAmount, tt.amount, tt.previous)
+			}
+			if sub.PriceIncreased() != (tt.amount > tt.previous) {
+				t.Errorf("detectSubscription() price increased = %v", sub.PriceIncreased())
+			}
+			if next := sub.Next.Format("2006-01-02"); next != tt.next {
+				t.Errorf("detectSubscription() next payment = %s, want %s", next, tt.next)
+			}
+		})
+	}
+}
@@ -1,8 +1,11 @@
 package api
 
 import (
+	"math/big"
 	"net/url"
+	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/apognu/n26/cli"
 )
@@ -27,3 +30,86 @@ func getSpaceFromID(spaces *cli.Spaces, id string) *cli.Space {
 	}
 	return nil
 }
+
+func normalizeIBAN(iban string) string {
+	return strings.ToUpper(strings.Replace(iban, " ", "", -1))
+}
+
+func isIBAN(iban string) bool {
+	iban = normalizeIBAN(iban)
+	if len(iban) < 15 || len(iban) > 34 {
+		return false
+	}
+
+	digits := ""
+	for _, c := range iban[4:] + iban[:4] {
+		switch {
+		case c >= '0' && c <= '9':
+			digits += string(c)
+		case c >= 'A' && c <= 'Z':
+			digits += strconv.Itoa(int(c-'A') + 10)
+		default:
+			return false
+		}
+	}
+
+	n, ok := new(big.Int).SetString(digits, 10)
+	if !ok {
+		return false
+	}
+
+	return n.Mod(n, big.NewInt(97)).Int64() == 1
+}
+
+func dryRun(requests ...*N26Request) cli.DryRunList {
+	list := make(cli.DryRunList, len(requests))
+
+	for idx, req := range requests {
+		body := req.Body
+		if mb, ok := body.(cli.MoneyBeam); ok {
+			mb.PIN = "<redacted>"
+			body = mb
+		}
+
+		list[idx] = cli.DryRun{Method: req.Method, Path: req.Path, Body: body}
+	}
+
+	return list
+}
+
+// Parallel runs the given jobs, at most workers of them at the same time,
+// and returns the first error encountered. Jobs run one at a time when
+// workers is lower than one.
+func Parallel(workers int, jobs ...func() error) error {
+	var (
+		wg   sync.WaitGroup
+		once sync.Once
+		err  error
+	)
+
+	if workers < 1 {
+		workers = 1
+	}
+
+	sem := make(chan struct{}, workers)
+
+	for _, job := range jobs {
+		wg.Add(1)
+		sem <- struct{}{}
+
+		go func(job func() error) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			if e := job(); e != nil {
+				once.Do(func() { err = e })
+			}
+		}(job)
+	}
+
+	wg.Wait()
+
+	return err
+}
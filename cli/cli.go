@@ -42,6 +42,20 @@ func Fatal(err error) {
 	logrus.Fatal(err)
 }
 
+func Info(msg string) {
+	logrus.Info(msg)
+}
+
+func Warn(err error) {
+	logrus.Warn(err)
+}
+
+// Interactive tells whether the standard input is a terminal someone can
+// answer prompts from.
+func Interactive() bool {
+	return terminal.IsTerminal(int(syscall.Stdin))
+}
+
 func ReadLine(prompt string) string {
 	fmt.Print(fmt.Sprintf("%s ", prompt))
 
@@ -53,7 +67,7 @@ func ReadLine(prompt string) string {
 
 func ReadSecret(prompt string) (string, error) {
 	fmt.Print(prompt)
-	secret, err := terminal.ReadPassword(syscall.Stdin)
+	secret, err := terminal.ReadPassword(int(syscall.Stdin))
 
 	line()
 
@@ -88,11 +102,24 @@ func attr(key, value string) {
 	fmt.Printf("%s %s\n", attrColor.Sprintf("%s:", key), value)
 }
 
-func Curr(amount float64, currency string) string {
-	return fmt.Sprintf("%.2f %s", amount, currency)
+func Curr(amount Amount, currency string) string {
+	return Money{Amount: amount, Currency: currency}.String()
 }
 
-func ConfirmSpaceTransfer(from, to *Space, amount float64) {
+func ConfirmSpaceTransfer(meta *Metadata, from, to *Space, amount Amount) {
+	if meta.AutoConfirm {
+		if !meta.GetPolicy().AllowsSpaceTransfer(from, to, amount) {
+			Fatal(fmt.Errorf("refusing to transfer %s from '%s' to '%s' outside of the confirmation policy", Curr(amount, from.Balance.Currency), from.Name, to.Name))
+		}
+
+		logrus.Infof("transfer of %s from '%s' to '%s' approved by policy", Curr(amount, from.Balance.Currency), from.Name, to.Name)
+		return
+	}
+
+	if meta.DryRun {
+		return
+	}
+
 	title("Please confirm you want to perform the following transfer")
 	line()
 
@@ -117,15 +144,21 @@ func ConfirmSpaceTransfer(from, to *Space, amount float64) {
 	}
 }
 
-func ConfirmMoneyBeam(trx MoneyBeamDetails, balance *Balance) {
+func ConfirmMoneyBeam(meta *Metadata, trx MoneyBeamDetails, balance *Balance) {
+	if meta.AutoConfirm {
+		confirmByPolicy(meta, BatchTransferList{{Recipient: transferRecipient(trx), Amount: trx.Amount}}, balance)
+		return
+	}
+
+	if meta.DryRun {
+		return
+	}
+
 	title("Please confirm you want to perform the following transfer")
 	fmt.Println("You will be asked for your PIN and will have to confirm the transfer from your paired device.")
 	line()
 
-	partnerID := trx.PartnerEmail
-	if trx.PartnerPhone != "" {
-		partnerID = trx.PartnerPhone
-	}
+	partnerID := transferRecipient(trx)
 
 	data := make([][]string, 3)
 	data[0] = []string{errColor.Sprintf("Main Account"), "→", Curr(trx.Amount, balance.Currency), "→", okColor.Sprintf(trx.PartnerName)}
@@ -141,3 +174,81 @@ func ConfirmMoneyBeam(trx MoneyBeamDetails, balance *Balance) {
 		Fatal(fmt.Errorf("the transfer was not performed"))
 	}
 }
+
+func ConfirmBatchTransfer(meta *Metadata, batch BatchTransferList, balance *Balance) {
+	if meta.AutoConfirm {
+		confirmByPolicy(meta, batch, balance)
+		return
+	}
+
+	if meta.DryRun {
+		return
+	}
+
+	title("Please confirm you want to perform the following transfers")
+	fmt.Println("You will be asked for your PIN once and will have to confirm each transfer from your paired device.")
+	line()
+
+	data := make([][]string, len(batch))
+	for idx, trx := range batch {
+		data[idx] = []string{
+			fmt.Sprintf("%d", idx+1),
+			okColor.Sprint(trx.Name),
+			attrColor.Sprint(trx.Recipient),
+			Curr(trx.Amount, balance.Currency),
+			trx.Reference,
+		}
+	}
+
+	table := table()
+	table.SetHeader([]string{"#", "Name", "Recipient", "Amount", "Reference"})
+	table.SetFooter([]string{"", "", "Total", Curr(batch.Total(), balance.Currency), ""})
+	table.AppendBulk(data)
+	table.Render()
+
+	line()
+	attr("Usable balance", Curr(balance.UsageBalance, balance.Currency))
+	attr("Balance after transfers", Curr(balance.UsageBalance-batch.Total(), balance.Currency))
+	line()
+
+	if ReadLine("Are you sure you want to perform these transfers? (y/N) ") != "y" {
+		Fatal(fmt.Errorf("the transfers were not performed"))
+	}
+}
+
+func confirmByPolicy(meta *Metadata, batch BatchTransferList, balance *Balance) {
+	for _, trx := range batch {
+		if !meta.GetPolicy().AllowsTransfer(trx.Recipient, trx.Amount) {
+			Fatal(fmt.Errorf("refusing to transfer %s to '%s' outside of the confirmation policy", Curr(trx.Amount, balance.Currency), trx.Recipient))
+		}
+	}
+
+	for _, trx := range batch {
+		logrus.Infof("transfer of %s to '%s' approved by policy", Curr(trx.Amount, balance.Currency), trx.Recipient)
+	}
+}
+
+func transferRecipient(trx MoneyBeamDetails) string {
+	switch {
+	case trx.PartnerPhone != "":
+		return trx.PartnerPhone
+	case trx.PartnerIBAN != "":
+		return trx.PartnerIBAN
+	}
+	return trx.PartnerEmail
+}
+
+func ConfirmAmount(meta *Metadata, amount Amount, currency string) {
+	if meta.AutoConfirm {
+		Fatal(fmt.Errorf("transfers of %s must be confirmed by typing their amount and cannot be approved automatically", Curr(amount, currency)))
+	}
+
+	if meta.DryRun {
+		return
+	}
+
+	typed, err := ParseMoney(ReadLine(fmt.Sprintf("Please type the amount of the transfer of %s to confirm it:", Curr(amount, currency))), currency)
+	if err != nil || typed != amount {
+		Fatal(fmt.Errorf("the amount does not match, the transfer was not performed"))
+	}
+}
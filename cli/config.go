@@ -0,0 +1,103 @@
+package cli
+
+type Config struct {
+	Credentials CredentialsConfig `json:"credentials"`
+	Policy      Policy            `json:"policy"`
+	Limits      Limits            `json:"limits"`
+	Rates       string            `json:"rates"`
+	Budgets     map[string]Amount `json:"budgets"`
+	Envelopes   []EnvelopeConfig  `json:"envelopes"`
+	Forecast    ForecastConfig    `json:"forecast"`
+}
+
+type ForecastConfig struct {
+	Threshold Amount `json:"threshold"`
+}
+
+// EnvelopeConfig links categories to a space funded every month with a
+// fixed amount, a percentage of the income of the month, or by default the
+// sum of the budgets of its categories.
+type EnvelopeConfig struct {
+	Space      string   `json:"space"`
+	Categories []string `json:"categories"`
+	Amount     Amount   `json:"amount"`
+	Percent    float64  `json:"percent"`
+}
+
+type CredentialsConfig struct {
+	Store                string `json:"store"`
+	PassEntry            string `json:"pass_entry"`
+	RefreshTokenLifetime string `json:"refresh_token_lifetime"`
+	PassphraseCache      string `json:"passphrase_cache"`
+}
+
+// Policy defines which money movements can be approved without any
+// interaction when running with --yes, and where to get the PIN from.
+type Policy struct {
+	PINEnv         string              `json:"pin_env"`
+	PINCommand     string              `json:"pin_command"`
+	SpaceTransfers []SpaceTransferRule `json:"space_transfers"`
+	Transfers      []TransferRule      `json:"transfers"`
+}
+
+type SpaceTransferRule struct {
+	From      string `json:"from"`
+	To        string `json:"to"`
+	MaxAmount Amount `json:"max_amount"`
+}
+
+type TransferRule struct {
+	Recipient string `json:"recipient"`
+	MaxAmount Amount `json:"max_amount"`
+}
+
+func (policy Policy) AllowsSpaceTransfer(from, to *Space, amount Amount) bool {
+	for _, rule := range policy.SpaceTransfers {
+		if matchSpace(rule.From, from) && matchSpace(rule.To, to) && withinLimit(rule.MaxAmount, amount) {
+			return true
+		}
+	}
+	return false
+}
+
+func (policy Policy) AllowsTransfer(recipient string, amount Amount) bool {
+	for _, rule := range policy.Transfers {
+		if (rule.Recipient == "*" || rule.Recipient == recipient) && withinLimit(rule.MaxAmount, amount) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchSpace(pattern string, space *Space) bool {
+	return pattern == "*" || pattern == space.ID || pattern == space.Name
+}
+
+// withinLimit requires every rule to set a maximum amount, so that a rule
+// missing one refuses everything instead of allowing any amount.
+func withinLimit(limit, amount Amount) bool {
+	return limit > 0 && amount <= limit
+}
+
+// Limits are enforced by the client before any money movement, on top of
+// the ones N26 enforces. Recipients and daily totals only concern money
+// leaving the account, not transfers between spaces.
+type Limits struct {
+	MaxTransfer        Amount      `json:"max_transfer"`
+	MaxDaily           Amount      `json:"max_daily"`
+	AllowedRecipients  []string    `json:"allowed_recipients"`
+	BlockedHours       []HourRange `json:"blocked_hours"`
+	ConfirmAmountAbove Amount      `json:"confirm_amount_above"`
+}
+
+type HourRange struct {
+	From int `json:"from"`
+	To   int `json:"to"`
+}
+
+func (hours HourRange) Contains(hour int) bool {
+	if hours.From <= hours.To {
+		return hour >= hours.From && hour < hours.To
+	}
+	return hour >= hours.From || hour < hours.To
+}
@@ -0,0 +1,66 @@
+package cli
+
+import "testing"
+
+func TestPolicyAllowsTransfer(t *testing.T) {
+	policy := Policy{Transfers: []TransferRule{
+		{Recipient: "john@example.com", MaxAmount: 100000},
+		{Recipient: "+4915100000000"},
+	}}
+	wildcard := Policy{Transfers: []TransferRule{{Recipient: "*", MaxAmount: 20000}}}
+
+	tests := []struct {
+		name      string
+		policy    Policy
+		recipient string
+		amount    Amount
+		allowed   bool
+	}{
+		{"no rule", Policy{}, "john@example.com", 1000, false},
+		{"matching rule", policy, "john@example.com", 1000, true},
+		{"at the maximum", policy, "john@example.com", 100000, true},
+		{"above the maximum", policy, "john@example.com", 100010, false},
+		{"other recipient", policy, "jane@example.com", 1000, false},
+		{"rule without maximum", policy, "+4915100000000", 10, false},
+		{"wildcard", wildcard, "jane@example.com", 20000, true},
+		{"wildcard above the maximum", wildcard, "jane@example.com", 20010, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if allowed := tt.policy.AllowsTransfer(tt.recipient, tt.amount); allowed != tt.allowed {
+				t.Errorf("AllowsTransfer(%q, %s) = %v, want %v", tt.recipient, tt.amount, allowed, tt.allowed)
+			}
+		})
+	}
+}
+
+func TestPolicyAllowsSpaceTransfer(t *testing.T) {
+	main := &Space{ID: "main-id", Name: "Main Account"}
+	savings := &Space{ID: "savings-id", Name: "Savings"}
+
+	policy := Policy{SpaceTransfers: []SpaceTransferRule{
+		{From: "Main Account", To: "savings-id", MaxAmount: 50000},
+		{From: "Savings", To: "*"},
+	}}
+
+	tests := []struct {
+		name     string
+		from, to *Space
+		amount   Amount
+		allowed  bool
+	}{
+		{"matching by name and ID", main, savings, 50000, true},
+		{"above the maximum", main, savings, 50010, false},
+		{"other direction without maximum", savings, main, 1000, false},
+		{"no rule", savings, savings, 1000, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if allowed := policy.AllowsSpaceTransfer(tt.from, tt.to, tt.amount); allowed != tt.allowed {
+				t.Errorf("AllowsSpaceTransfer(%s, %s, %s) = %v, want %v", tt.from.Name, tt.to.Name, tt.amount, allowed, tt.allowed)
+			}
+		})
+	}
+}
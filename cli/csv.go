@@ -0,0 +1,56 @@
+package cli
+
+import (
+	"encoding/csv"
+	"os"
+	"strconv"
+)
+
+func CSV(rows [][]string) {
+	writer := csv.NewWriter(os.Stdout)
+	if err := writer.WriteAll(rows); err != nil {
+		Fatal(err)
+	}
+}
+
+func (trend Trend) CSV(meta *Metadata) {
+	header := []string{"category", "type"}
+	for _, month := range trend.Months {
+		header = append(header, month.Format("2006-01"))
+	}
+	header = append(header, "average", "mom", "yoy")
+
+	rows := [][]string{header}
+
+	for _, m := range trend.Movements {
+		for _, s := range []struct {
+			kind    string
+			values  []Amount
+			yearAgo Amount
+		}{{"income", m.Income, m.YearAgoIncome}, {"expense", m.Expense, m.YearAgoExpense}} {
+			if total(s.values) == 0 {
+				continue
+			}
+
+			row := []string{trend.Categories[m.Category], s.kind}
+			for _, v := range s.values {
+				row = append(row, v.String())
+			}
+			row = append(row, average(s.values).String())
+
+			row = append(row, formatChange(monthOverMonth(s.values)), formatChange(yearOverYear(s.values, s.yearAgo)))
+
+			rows = append(rows, row)
+		}
+	}
+
+	CSV(rows)
+}
+
+func formatChange(ratio float64, ok bool) string {
+	if !ok {
+		return ""
+	}
+
+	return strconv.FormatFloat(ratio, 'f', 4, 64)
+}
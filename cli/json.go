@@ -3,7 +3,6 @@ package cli
 import (
 	"encoding/json"
 	"fmt"
-	"time"
 
 	"github.com/sirupsen/logrus"
 )
@@ -22,8 +21,24 @@ func (msg SimpleMessage) JSON(meta *Metadata) {
 	logrus.Info(msg)
 }
 
+func (requests DryRunList) JSON(meta *Metadata) {
+	JSON(requests)
+}
+
+func (status AuthStatus) JSON(meta *Metadata) {
+	JSON(status)
+}
+
+func (profiles ProfileList) JSON(meta *Metadata) {
+	JSON(profiles)
+}
+
+func (log AuditLog) JSON(meta *Metadata) {
+	JSON(log)
+}
+
 func (info PersonalInformation) JSON(meta *Metadata) {
-	bd := time.Unix(info.BirthDate/1000, 0)
+	bd := FromMillis(info.BirthDate)
 
 	JSON(js{
 		"name":        fmt.Sprintf("%s %s", info.Firstname, info.Lastname),
@@ -46,6 +61,7 @@ func (balance Balance) JSON(meta *Metadata) {
 	JSON(js{
 		"balance":        balance.AvailableBalance,
 		"usable_balance": balance.UsageBalance,
+		"currency":       balance.Currency,
 	})
 }
 
@@ -53,7 +69,7 @@ func (cards CardList) JSON(meta *Metadata) {
 	data := make([]js, len(cards))
 
 	for idx, card := range cards {
-		exp := time.Unix(card.Expiration/1000, 0)
+		exp := FromMillis(card.Expiration)
 		model := card.ProductType
 		if card.ProductType != card.Design {
 			model = fmt.Sprintf("%s/%s", card.ProductType, card.Design)
@@ -94,7 +110,7 @@ func (limits LimitList) JSON(meta *Metadata) {
 func (transactions PastTransactionList) JSON(meta *Metadata) {
 	data := make([]js, len(transactions))
 	for idx, trx := range transactions {
-		date := time.Unix(trx.Date/1000, 0)
+		date := FromMillis(trx.Date)
 
 		party := trx.MerchantName
 		if trx.Partner != "" {
@@ -109,10 +125,23 @@ func (transactions PastTransactionList) JSON(meta *Metadata) {
 			"date":        date.Format("02 Jan 2006 15:04"),
 			"third_party": party,
 			"amount":      trx.Amount,
+			"currency":    trx.Currency,
 			"category":    meta.GetCategory(trx.Category),
 			"location":    trx.MerchantCity,
 			"comment":     trx.Comment,
 		}
+
+		if trx.Foreign() {
+			data[idx]["original_amount"] = trx.OriginalAmount
+			data[idx]["original_currency"] = trx.OriginalCurrency
+			data[idx]["exchange_rate"] = trx.ExchangeRate
+			data[idx]["fx_fee"] = trx.FXFee()
+		}
+
+		if trx.Report != nil {
+			data[idx]["report_amount"] = trx.Report.Amount
+			data[idx]["report_currency"] = trx.Report.Currency
+		}
 	}
 
 	JSON(data)
@@ -123,14 +152,15 @@ func (spaces Spaces) JSON(meta *Metadata) {
 
 	for idx, space := range spaces.Spaces {
 		data[idx] = js{
-			"id":      space.ID,
-			"name":    space.Name,
-			"primary": true,
-			"amount":  space.Balance.AvailableBalance,
+			"id":       space.ID,
+			"name":     space.Name,
+			"primary":  true,
+			"amount":   space.Balance.AvailableBalance,
+			"currency": space.Balance.Currency,
 		}
 
 		if space.Goal.Amount > 0 {
-			progress := space.Balance.AvailableBalance / space.Goal.Amount
+			progress := space.Balance.AvailableBalance.Ratio(space.Goal.Amount)
 
 			data[idx]["goal"] = space.Goal.Amount
 			data[idx]["progress"] = progress
@@ -143,11 +173,23 @@ func (spaces Spaces) JSON(meta *Metadata) {
 func (stats Statistics) JSON(meta *Metadata) {
 	data := js{
 		"global": js{
-			"income":  stats.TotalIncome,
-			"expense": stats.TotalExpense,
+			"income":   stats.TotalIncome,
+			"expense":  stats.TotalExpense,
+			"fx_fees":  stats.FXFees,
+			"currency": stats.Currency,
 		},
-		"income":  js{},
-		"expense": js{},
+		"income":     js{},
+		"expense":    js{},
+		"currencies": js{},
+	}
+
+	for _, c := range stats.Currencies {
+		data["currencies"].(js)[c.Currency] = js{
+			"transactions": c.Transactions,
+			"income":       c.Income,
+			"expense":      c.Expense,
+			"fx_fees":      c.FXFees,
+		}
 	}
 
 	for _, m := range stats.Movements {
@@ -161,3 +203,152 @@ func (stats Statistics) JSON(meta *Metadata) {
 
 	JSON(data)
 }
+
+func (trend Trend) JSON(meta *Metadata) {
+	months := make([]string, len(trend.Months))
+	for idx, month := range trend.Months {
+		months[idx] = month.Format("2006-01")
+	}
+
+	series := func(values []Amount, yearAgo Amount) js {
+		deviating := make([]string, 0)
+		for idx := range values {
+			if deviation(values, idx) != 0 {
+				deviating = append(deviating, months[idx])
+			}
+		}
+
+		data := js{
+			"values":           values,
+			"average":          average(values),
+			"deviating_months": deviating,
+			"mom":              nil,
+			"yoy":              nil,
+		}
+
+		if ratio, ok := monthOverMonth(values); ok {
+			data["mom"] = ratio
+		}
+		if ratio, ok := yearOverYear(values, yearAgo); ok {
+			data["yoy"] = ratio
+		}
+
+		return data
+	}
+
+	data := js{
+		"months":  months,
+		"income":  js{},
+		"expense": js{},
+	}
+
+	for _, m := range trend.Movements {
+		if total(m.Income) > 0 {
+			data["income"].(js)[trend.Categories[m.Category]] = series(m.Income, m.YearAgoIncome)
+		}
+		if total(m.Expense) > 0 {
+			data["expense"].(js)[trend.Categories[m.Category]] = series(m.Expense, m.YearAgoExpense)
+		}
+	}
+
+	JSON(data)
+}
+
+func (status BudgetStatus) JSON(meta *Metadata) {
+	data := make([]js, len(status.Budgets))
+
+	for idx, b := range status.Budgets {
+		data[idx] = js{
+			"category":              status.Categories[b.Category],
+			"category_id":           b.Category,
+			"budget":                b.Limit,
+			"spent":                 b.Spent,
+			"remaining":             b.Remaining(),
+			"projected":             status.Projected(b),
+			"over_budget":           b.Spent > b.Limit,
+			"projected_over_budget": status.Projected(b) > b.Limit,
+		}
+	}
+
+	JSON(js{
+		"month":   status.Month.Format("2006-01"),
+		"day":     status.Day,
+		"days":    status.Days,
+		"budgets": data,
+	})
+}
+
+func (plan EnvelopePlan) JSON(meta *Metadata) {
+	envelopes := make([]js, len(plan.Envelopes))
+
+	for idx, envelope := range plan.Envelopes {
+		envelopes[idx] = js{
+			"space":    envelope.Space,
+			"space_id": envelope.SpaceID,
+			"target":   envelope.Target,
+			"spent":    envelope.Spent,
+			"needed":   envelope.Needed(),
+			"balance":  envelope.Balance,
+			"transfer": envelope.Transfer(),
+		}
+	}
+
+	JSON(js{
+		"month":       plan.Month.Format("2006-01"),
+		"currency":    plan.Currency,
+		"income":      plan.Income,
+		"allocated":   plan.Allocated(),
+		"unallocated": plan.Unallocated(),
+		"available":   plan.Available,
+		"transfers":   plan.Transfers(),
+		"envelopes":   envelopes,
+	})
+}
+
+func (subscriptions SubscriptionList) JSON(meta *Metadata) {
+	data := make([]js, len(subscriptions))
+
+	for idx, sub := range subscriptions {
+		data[idx] = js{
+			"name":            sub.Name,
+			"cadence":         sub.Cadence,
+			"occurrences":     sub.Occurrences,
+			"amount":          sub.Amount,
+			"previous_amount": sub.PreviousAmount,
+			"currency":        sub.Currency,
+			"monthly":         sub.Monthly,
+			"yearly":          sub.Yearly,
+			"last":            sub.Last.Format("2006-01-02"),
+			"next":            sub.Next.Format("2006-01-02"),
+			"price_increased": sub.PriceIncreased(),
+			"missed":          sub.Missed,
+		}
+	}
+
+	JSON(data)
+}
+
+func (forecast Forecast) JSON(meta *Metadata) {
+	series := make([]js, len(forecast.Days))
+
+	for idx, day := range forecast.Days {
+		movements := make([]js, len(day.Movements))
+		for i, m := range day.Movements {
+			movements[i] = js{"name": m.Name, "amount": m.Amount}
+		}
+
+		series[idx] = js{
+			"date":            day.Date.Format("2006-01-02"),
+			"balance":         day.Balance,
+			"below_threshold": day.Balance < forecast.Threshold,
+			"movements":       movements,
+		}
+	}
+
+	JSON(js{
+		"currency":  forecast.Currency,
+		"balance":   forecast.Balance,
+		"threshold": forecast.Threshold,
+		"series":    series,
+	})
+}
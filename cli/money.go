@@ -0,0 +1,268 @@
+package cli
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Amount is an exact amount of money in thousandths of the currency unit,
+// which is precise enough for every ISO 4217 currency, so sums and
+// differences never suffer from floating point rounding.
+type Amount int64
+
+// Money is an amount in a given ISO 4217 currency.
+type Money struct {
+	Amount   Amount `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+const (
+	scale    = 1000
+	decimals = 3
+)
+
+// exponents are the ISO 4217 currencies whose minor unit is not a cent.
+var exponents = map[string]int{
+	"BIF": 0, "CLP": 0, "DJF": 0, "GNF": 0, "ISK": 0, "JPY": 0, "KMF": 0,
+	"KRW": 0, "PYG": 0, "RWF": 0, "UGX": 0, "UYI": 0, "VND": 0, "VUV": 0,
+	"XAF": 0, "XOF": 0, "XPF": 0,
+	"BHD": 3, "IQD": 3, "JOD": 3, "KWD": 3, "LYD": 3, "OMR": 3, "TND": 3,
+}
+
+// Exponent returns the number of decimals of the minor unit of a currency,
+// which is two for most of them.
+func Exponent(currency string) int {
+	if exponent, ok := exponents[strings.ToUpper(currency)]; ok {
+		return exponent
+	}
+	return 2
+}
+
+// minorUnit returns the number of thousandths in the minor unit of a
+// currency.
+func minorUnit(currency string) Amount {
+	unit := Amount(1)
+	for idx := Exponent(currency); idx < decimals; idx++ {
+		unit *= 10
+	}
+	return unit
+}
+
+// ParseAmount parses a decimal amount in a currency that is not known yet,
+// refusing anything more precise than a thousandth, the smallest minor unit
+// of any currency. It must be checked against the currency once known.
+func ParseAmount(s string) (Amount, error) {
+	amount, exact, err := parseAmount(s)
+	if err != nil {
+		return 0, err
+	}
+	if !exact {
+		return 0, fmt.Errorf("'%s' is more precise than a thousandth", s)
+	}
+	return amount, nil
+}
+
+// ParseMoney parses a decimal amount in a currency, refusing anything more
+// precise than its minor unit.
+func ParseMoney(s, currency string) (Amount, error) {
+	amount, err := ParseAmount(s)
+	if err != nil {
+		return 0, err
+	}
+	if err := amount.Check(currency); err != nil {
+		return 0, fmt.Errorf("'%s' is more precise than the minor unit of %s", s, currency)
+	}
+	return amount, nil
+}
+
+// Check refuses an amount more precise than the minor unit of a currency.
+func (amount Amount) Check(currency string) error {
+	if amount.Round(currency) != amount {
+		return fmt.Errorf("%s is more precise than the minor unit of %s", amount, currency)
+	}
+	return nil
+}
+
+// parseAmount parses a decimal amount, rounding it half away from zero to
+// the nearest thousandth.
+func parseAmount(s string) (Amount, bool, error) {
+	r, ok := new(big.Rat).SetString(strings.TrimSpace(s))
+	if !ok {
+		return 0, false, fmt.Errorf("could not parse amount '%s'", s)
+	}
+
+	r.Mul(r, big.NewRat(scale, 1))
+
+	if new(big.Int).Abs(r.Num()).Cmp(new(big.Int).Mul(big.NewInt(math.MaxInt64), r.Denom())) > 0 {
+		return 0, false, fmt.Errorf("amount '%s' is too large", s)
+	}
+
+	return round(r), r.IsInt(), nil
+}
+
+// round rounds a number of thousandths half away from zero.
+func round(r *big.Rat) Amount {
+	if r.IsInt() {
+		return Amount(r.Num().Int64())
+	}
+
+	half := big.NewRat(1, 2)
+	if r.Sign() < 0 {
+		half.Neg(half)
+	}
+	r = new(big.Rat).Add(r, half)
+
+	return Amount(new(big.Int).Quo(r.Num(), r.Denom()).Int64())
+}
+
+// Round rounds the amount half away from zero to the minor unit of a
+// currency.
+func (amount Amount) Round(currency string) Amount {
+	unit := minorUnit(currency)
+	if unit == 1 {
+		return amount
+	}
+	return round(big.NewRat(int64(amount), int64(unit))) * unit
+}
+
+func rate(rate float64) *big.Rat {
+	r, ok := new(big.Rat).SetString(strconv.FormatFloat(rate, 'f', -1, 64))
+	if !ok {
+		return new(big.Rat)
+	}
+	return r
+}
+
+// Mul multiplies the amount by an exchange rate, rounding to the nearest
+// thousandth.
+func (amount Amount) Mul(r float64) Amount {
+	return round(new(big.Rat).Mul(big.NewRat(int64(amount), 1), rate(r)))
+}
+
+// Div divides the amount by an exchange rate, rounding to the nearest
+// thousandth.
+func (amount Amount) Div(r float64) Amount {
+	if r == 0 {
+		return 0
+	}
+	return round(new(big.Rat).Quo(big.NewRat(int64(amount), 1), rate(r)))
+}
+
+// Exchange converts an amount between two currencies quoted against the same
+// reference, rounding to the nearest thousandth.
+func (amount Amount) Exchange(from, to float64) Amount {
+	if from == 0 {
+		return 0
+	}
+
+	r := new(big.Rat).Mul(big.NewRat(int64(amount), 1), rate(to))
+	return round(r.Quo(r, rate(from)))
+}
+
+func (amount Amount) Abs() Amount {
+	if amount < 0 {
+		return -amount
+	}
+	return amount
+}
+
+// Float returns an approximation of the amount, for ratios and percentages.
+func (amount Amount) Float() float64 {
+	return float64(amount) / scale
+}
+
+// Ratio returns what fraction of total the amount represents.
+func (amount Amount) Ratio(total Amount) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(amount) / float64(total)
+}
+
+// String writes the amount with two decimals, or three when it is more
+// precise than a cent.
+func (amount Amount) String() string {
+	if amount.Round("") != amount {
+		return amount.format(3)
+	}
+	return amount.format(2)
+}
+
+// Format writes the amount with the decimals of a currency.
+func (amount Amount) Format(currency string) string {
+	return amount.Round(currency).format(Exponent(currency))
+}
+
+func (amount Amount) format(exponent int) string {
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+	}
+
+	units := fmt.Sprintf("%d", uint64(amount.Abs())/scale)
+	if exponent == 0 {
+		return sign + units
+	}
+
+	fraction := fmt.Sprintf("%03d", uint64(amount.Abs())%scale)
+	return fmt.Sprintf("%s%s.%s", sign, units, fraction[:exponent])
+}
+
+// MarshalJSON writes the amount as a JSON number without trailing zeros, the
+// way a float64 holding the same value would be written.
+func (amount Amount) MarshalJSON() ([]byte, error) {
+	s := amount.String()
+	s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	if s == "" || s == "-" {
+		s = "0"
+	}
+	return []byte(s), nil
+}
+
+// UnmarshalJSON reads an amount exactly, refusing amounts that could only be
+// stored by rounding them.
+func (amount *Amount) UnmarshalJSON(data []byte) error {
+	s := strings.Trim(string(data), `"`)
+	if s == "null" {
+		return nil
+	}
+
+	a, exact, err := parseAmount(s)
+	if err != nil {
+		return err
+	}
+	if !exact {
+		return fmt.Errorf("amount '%s' is more precise than a thousandth", s)
+	}
+
+	*amount = a
+	return nil
+}
+
+// Set allows amounts to be used as command-line arguments, which are checked
+// against the currency of the account once it is known.
+func (amount *Amount) Set(s string) error {
+	a, err := ParseAmount(s)
+	if err != nil {
+		return err
+	}
+
+	*amount = a
+	return nil
+}
+
+// Add sums two amounts of money, refusing to combine different currencies.
+func (money Money) Add(other Money) (Money, error) {
+	if !strings.EqualFold(money.Currency, other.Currency) {
+		return Money{}, fmt.Errorf("cannot add %s to %s", other, money)
+	}
+
+	return Money{Amount: money.Amount + other.Amount, Currency: money.Currency}, nil
+}
+
+func (money Money) String() string {
+	return strings.TrimSpace(fmt.Sprintf("%s %s", money.Amount.Format(money.Currency), money.Currency))
+}
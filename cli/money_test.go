@@ -0,0 +1,187 @@
+package cli
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseAmount(t *testing.T) {
+	tests := []struct {
+		in     string
+		amount Amount
+		out    string
+		err    bool
+	}{
+		{in: "0", amount: 0, out: "0.00"},
+		{in: "12.3", amount: 12300, out: "12.30"},
+		{in: " 150.00 ", amount: 150000, out: "150.00"},
+		{in: "-42.05", amount: -42050, out: "-42.05"},
+		{in: "-0.01", amount: -10, out: "-0.01"},
+		{in: "0.1", amount: 100, out: "0.10"},
+		{in: "92233720368547.75", amount: 92233720368547750, out: "92233720368547.75"},
+		{in: "-92233720368547.75", amount: -92233720368547750, out: "-92233720368547.75"},
+		{in: "9223372036854775.80", amount: 9223372036854775800, out: "9223372036854775.80"},
+		{in: "0.005", amount: 5, out: "0.005"},
+		{in: "-1.999", amount: -1999, out: "-1.999"},
+		{in: "0.0005", err: true},
+		{in: "9223372036854775.81", err: true},
+		{in: "1e30", err: true},
+		{in: "ten", err: true},
+		{in: "", err: true},
+	}
+
+	for _, tt := range tests {
+		amount, err := ParseAmount(tt.in)
+
+		if tt.err {
+			if err == nil {
+				t.Errorf("ParseAmount(%q) = %d, want an error", tt.in, amount)
+			}
+			continue
+		}
+
+		if err != nil || amount != tt.amount {
+			t.Errorf("ParseAmount(%q) = (%d, %v), want %d", tt.in, amount, err, tt.amount)
+			continue
+		}
+
+		if amount.String() != tt.out {
+			t.Errorf("ParseAmount(%q).String() = %q, want %q", tt.in, amount.String(), tt.out)
+		}
+
+		if again, err := ParseAmount(amount.String()); err != nil || again != amount {
+			t.Errorf("ParseAmount(%q) = (%d, %v), want %d", amount.String(), again, err, amount)
+		}
+	}
+}
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		in       string
+		currency string
+		amount   Amount
+		out      string
+		err      bool
+	}{
+		{in: "12.34", currency: "EUR", amount: 12340, out: "12.34 EUR"},
+		{in: "1234", currency: "JPY", amount: 1234000, out: "1234 JPY"},
+		{in: "-1234", currency: "jpy", amount: -1234000, out: "-1234 jpy"},
+		{in: "0.005", currency: "KWD", amount: 5, out: "0.005 KWD"},
+		{in: "-1.234", currency: "BHD", amount: -1234, out: "-1.234 BHD"},
+		{in: "12.5", currency: "JPY", err: true},
+		{in: "0.005", currency: "EUR", err: true},
+		{in: "0.0005", currency: "KWD", err: true},
+	}
+
+	for _, tt := range tests {
+		amount, err := ParseMoney(tt.in, tt.currency)
+
+		if tt.err {
+			if err == nil {
+				t.Errorf("ParseMoney(%q, %q) = %d, want an error", tt.in, tt.currency, amount)
+			}
+			continue
+		}
+
+		if err != nil || amount != tt.amount {
+			t.Errorf("ParseMoney(%q, %q) = (%d, %v), want %d", tt.in, tt.currency, amount, err, tt.amount)
+			continue
+		}
+
+		if out := Curr(amount, tt.currency); out != tt.out {
+			t.Errorf("Curr(%d, %q) = %q, want %q", amount, tt.currency, out, tt.out)
+		}
+	}
+}
+
+func TestAmountRound(t *testing.T) {
+	tests := []struct {
+		amount   Amount
+		currency string
+		rounded  Amount
+	}{
+		{12345, "EUR", 12350},
+		{-12345, "EUR", -12350},
+		{12344, "EUR", 12340},
+		{5, "EUR", 10},
+		{-5, "EUR", -10},
+		{4, "EUR", 0},
+		{1500, "JPY", 2000},
+		{-1499, "JPY", -1000},
+		{1234, "KWD", 1234},
+	}
+
+	for _, tt := range tests {
+		if rounded := tt.amount.Round(tt.currency); rounded != tt.rounded {
+			t.Errorf("Amount(%d).Round(%q) = %d, want %d", tt.amount, tt.currency, rounded, tt.rounded)
+		}
+	}
+}
+
+func TestAmountJSON(t *testing.T) {
+	tests := []struct {
+		in     string
+		amount Amount
+		out    string
+		err    bool
+	}{
+		{in: `12.3`, amount: 12300, out: `12.3`},
+		{in: `"12.30"`, amount: 12300, out: `12.3`},
+		{in: `-0.01`, amount: -10, out: `-0.01`},
+		{in: `100`, amount: 100000, out: `100`},
+		{in: `0.005`, amount: 5, out: `0.005`},
+		{in: `1.0005`, err: true},
+		{in: `-1.0005`, err: true},
+		{in: `0`, amount: 0, out: `0`},
+	}
+
+	for _, tt := range tests {
+		var amount Amount
+		err := json.Unmarshal([]byte(tt.in), &amount)
+
+		if tt.err {
+			if err == nil {
+				t.Errorf("json.Unmarshal(%s) = %d, want an error", tt.in, amount)
+			}
+			continue
+		}
+
+		if err != nil || amount != tt.amount {
+			t.Errorf("json.Unmarshal(%s) = (%d, %v), want %d", tt.in, amount, err, tt.amount)
+			continue
+		}
+
+		out, err := json.Marshal(amount)
+		if err != nil || string(out) != tt.out {
+			t.Errorf("json.Marshal(%d) = (%s, %v), want %s", amount, out, err, tt.out)
+		}
+	}
+}
+
+func TestMoneyAdd(t *testing.T) {
+	tests := []struct {
+		a, b Money
+		sum  Money
+		err  bool
+	}{
+		{a: Money{100, "EUR"}, b: Money{250, "EUR"}, sum: Money{350, "EUR"}},
+		{a: Money{100, "EUR"}, b: Money{250, "eur"}, sum: Money{350, "EUR"}},
+		{a: Money{100, "EUR"}, b: Money{250, "USD"}, err: true},
+		{a: Money{100, "EUR"}, b: Money{250, ""}, err: true},
+	}
+
+	for _, tt := range tests {
+		sum, err := tt.a.Add(tt.b)
+
+		if tt.err {
+			if err == nil {
+				t.Errorf("%v.Add(%v) = %v, want an error", tt.a, tt.b, sum)
+			}
+			continue
+		}
+
+		if err != nil || sum != tt.sum {
+			t.Errorf("%v.Add(%v) = (%v, %v), want %v", tt.a, tt.b, sum, err, tt.sum)
+		}
+	}
+}
@@ -1,11 +1,15 @@
 package cli
 
 import (
+	"encoding/json"
 	"fmt"
 	"math"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/fatih/color"
 	"github.com/pmylund/sortutil"
 	"github.com/sirupsen/logrus"
 )
@@ -14,8 +18,99 @@ func (msg SimpleMessage) Print(meta *Metadata) {
 	logrus.Info(msg)
 }
 
+func (requests DryRunList) Print(meta *Metadata) {
+	title("Dry run, the following requests would have been sent")
+	line()
+
+	for _, req := range requests {
+		attr("Request", fmt.Sprintf("%s %s", req.Method, req.Path))
+
+		if req.Body != nil {
+			body, _ := json.MarshalIndent(req.Body, "    ", "  ")
+			attr("Body", string(body))
+		}
+
+		line()
+	}
+}
+
+func (status AuthStatus) Print(meta *Metadata) {
+	title("Authentication")
+
+	attr("Profile", status.Profile)
+	attr("Credential store", status.Store)
+	attr("Device token", attrColor.Sprint(status.DeviceToken))
+
+	if !status.LoggedIn {
+		attr("Status", errColor.Sprint("LOGGED OUT"))
+		return
+	}
+
+	attr("Status", okColor.Sprint("LOGGED IN"))
+	attr("Token type", status.TokenType)
+	if status.Expiry.After(time.Now()) {
+		attr("Access token expires", status.Expiry.In(Location).Format("02 Jan 2006 15:04"))
+	} else {
+		attr("Access token expires", warnColor.Sprint("EXPIRED"))
+	}
+	if status.Refreshable {
+		attr("Refresh token", "present")
+	} else {
+		attr("Refresh token", errColor.Sprint("missing"))
+	}
+	if !status.RefreshExpiry.IsZero() {
+		attr("Session expires", status.RefreshExpiry.In(Location).Format("02 Jan 2006 15:04"))
+	}
+}
+
+func (profiles ProfileList) Print(meta *Metadata) {
+	title("Profiles")
+
+	for _, profile := range profiles {
+		if profile.Default {
+			fmt.Printf("  %s %s\n", profile.Name, okColor.Sprint("(DEFAULT)"))
+		} else {
+			fmt.Printf("  %s\n", profile.Name)
+		}
+	}
+}
+
+func (log AuditLog) Print(meta *Metadata) {
+	headers := []string{
+		"Date",
+		"Action",
+		"Recipient",
+		"Amount",
+		"Status",
+		"Message",
+	}
+
+	data := make([][]string, len(log))
+	for idx, entry := range log {
+		status := okColor.Sprint(entry.Status)
+		if entry.Status == 0 || entry.Status > 399 {
+			status = errColor.Sprint(entry.Status)
+		}
+
+		data[idx] = []string{
+			titleColor.Sprint(entry.Timestamp.In(Location).Format("02 Jan 2006 15:04")),
+			entry.Action,
+			entry.Recipient,
+			Money{Amount: entry.Amount, Currency: entry.Currency}.String(),
+			status,
+			attrColor.Sprint(entry.Message),
+		}
+	}
+
+	table := table()
+	table.SetHeader(headers)
+	table.AppendBulk(data)
+
+	table.Render()
+}
+
 func (info PersonalInformation) Print(meta *Metadata) {
-	bd := time.Unix(info.BirthDate/1000, 0)
+	bd := FromMillis(info.BirthDate)
 
 	title("Card holder")
 
@@ -37,15 +132,15 @@ func (account Account) Print(meta *Metadata) {
 func (balance Balance) Print(meta *Metadata) {
 	title("Account balance")
 
-	attr("Balance", fmt.Sprintf("%.2f %s", balance.AvailableBalance, balance.Currency))
+	attr("Balance", Curr(balance.AvailableBalance, balance.Currency))
 	if balance.AvailableBalance != balance.UsageBalance {
-		attr("Usable balance", fmt.Sprintf("%.2f %s", balance.UsageBalance, balance.Currency))
+		attr("Usable balance", Curr(balance.UsageBalance, balance.Currency))
 	}
 }
 
 func (cards CardList) Print(meta *Metadata) {
 	for _, card := range cards {
-		exp := time.Unix(card.Expiration/1000, 0)
+		exp := FromMillis(card.Expiration)
 		model := card.ProductType
 		if card.ProductType != card.Design {
 			model = fmt.Sprintf("%s/%s", card.ProductType, card.Design)
@@ -73,9 +168,9 @@ func (limits LimitList) Print(meta *Metadata) {
 
 	for _, limit := range limits {
 		if l, ok := LimitStatuses[limit.Limit]; ok {
-			attr(l, fmt.Sprintf("%.2f", limit.Amount))
+			attr(l, limit.Amount.String())
 		} else {
-			attr(limit.Limit, fmt.Sprintf("%.2f", limit.Amount))
+			attr(limit.Limit, limit.Amount.String())
 		}
 	}
 }
@@ -92,16 +187,22 @@ func (transactions PastTransactionList) Print(meta *Metadata) {
 
 	data := make([][]string, len(transactions))
 	for idx, trx := range transactions {
-		date := time.Unix(trx.Date/1000, 0)
+		date := FromMillis(trx.Date)
 
 		party := trx.MerchantName
 		if trx.Partner != "" {
 			party = trx.Partner
 		}
 
-		amount := okColor.Sprintf("← %.2f %s", trx.Amount, trx.Currency)
+		amount := okColor.Sprintf("← %s", Curr(trx.Amount, trx.Currency))
 		if trx.Amount < 0 {
-			amount = errColor.Sprintf("→ %.2f %s", math.Abs(trx.Amount), trx.Currency)
+			amount = errColor.Sprintf("→ %s", Curr(trx.Amount.Abs(), trx.Currency))
+		}
+		if trx.Foreign() {
+			amount = fmt.Sprintf("%s %s", amount, attrColor.Sprintf("(%s @%s)", Curr(trx.OriginalAmount.Abs(), trx.OriginalCurrency), strconv.FormatFloat(trx.ExchangeRate, 'f', -1, 64)))
+		}
+		if trx.Report != nil {
+			amount = fmt.Sprintf("%s = %s", amount, Curr(trx.Report.Amount.Abs(), trx.Report.Currency))
 		}
 
 		if trx.Scheme == "SPACES" {
@@ -134,11 +235,11 @@ func (spaces Spaces) Print(meta *Metadata) {
 		}
 
 		attr("ID", attrColor.Sprintf(space.ID))
-		attr("Amount", fmt.Sprintf("%.2f %s", space.Balance.AvailableBalance, space.Balance.Currency))
+		attr("Amount", Curr(space.Balance.AvailableBalance, space.Balance.Currency))
 		if space.Goal.Amount > 0 {
-			progress := space.Balance.AvailableBalance / space.Goal.Amount * 100
+			progress := space.Balance.AvailableBalance.Ratio(space.Goal.Amount) * 100
 
-			attr("Goal", fmt.Sprintf("%.2f %s", space.Goal.Amount, space.Balance.Currency))
+			attr("Goal", Curr(space.Goal.Amount, space.Balance.Currency))
 			attr("Progress", fmt.Sprintf("%.1f %%", progress))
 		}
 
@@ -148,9 +249,31 @@ func (spaces Spaces) Print(meta *Metadata) {
 
 func (stats Statistics) Print(meta *Metadata) {
 	title("Global movements")
-	attr("Period", fmt.Sprintf("%s - %s", time.Unix(stats.From/1000, 0).Format("02 Jan 2006"), time.Unix(stats.To/1000, 0).Format("02 Jan 2006")))
-	attr("Income", fmt.Sprintf("%.2f", stats.TotalIncome))
-	attr("Expense", fmt.Sprintf("%.2f", stats.TotalExpense))
+	attr("Period", fmt.Sprintf("%s - %s", FromMillis(stats.From).Format("02 Jan 2006"), FromMillis(stats.To).Format("02 Jan 2006")))
+	attr("Income", stats.TotalIncome.String())
+	attr("Expense", stats.TotalExpense.String())
+	if stats.FXFees > 0 {
+		attr("FX fees", Curr(stats.FXFees, stats.Currency))
+	}
+
+	if len(stats.Currencies) > 1 {
+		line()
+		title("Movements by currency")
+		line()
+
+		currencies := table()
+		currencies.SetHeader([]string{"Currency", "Transactions", "Income", "Expense", "FX fees"})
+		for _, c := range stats.Currencies {
+			currencies.Append([]string{
+				c.Currency,
+				strconv.Itoa(c.Transactions),
+				c.Income.String(),
+				c.Expense.String(),
+				Curr(c.FXFees, stats.Currency),
+			})
+		}
+		currencies.Render()
+	}
 
 	line()
 	title("Income by category")
@@ -162,12 +285,12 @@ func (stats Statistics) Print(meta *Metadata) {
 	income.SetHeader([]string{"Category", "Income", "Income %"})
 	sortutil.DescByField(stats.Movements, "Income")
 	for _, m := range stats.Movements {
-		pct := m.Income / stats.TotalIncome * 100
+		pct := m.Income.Ratio(stats.TotalIncome) * 100
 		prog := strings.Repeat("▪", int(pct)/int(100/progressLength))
 
 		income.Append([]string{
 			stats.Categories[m.Category],
-			fmt.Sprintf("%.2f", m.Income),
+			m.Income.String(),
 			fmt.Sprintf("%.1f %%", pct),
 			prog,
 		})
@@ -182,15 +305,338 @@ func (stats Statistics) Print(meta *Metadata) {
 	expense.SetHeader([]string{"Category", "Expense", "Expense %"})
 	sortutil.DescByField(stats.Movements, "Expense")
 	for _, m := range stats.Movements {
-		pct := m.Expense / stats.TotalExpense * 100
+		pct := m.Expense.Ratio(stats.TotalExpense) * 100
 		prog := strings.Repeat("▪", int(pct)/int(100/progressLength))
 
 		expense.Append([]string{
 			stats.Categories[m.Category],
-			fmt.Sprintf("%.2f", m.Expense),
-			fmt.Sprintf("%.1f %%", m.Expense/stats.TotalExpense*100),
+			m.Expense.String(),
+			fmt.Sprintf("%.1f %%", pct),
 			prog,
 		})
 	}
 	expense.Render()
 }
+
+func (trend Trend) Print(meta *Metadata) {
+	title("Income by month")
+	line()
+	trend.render(func(m CategoryTrend) ([]Amount, Amount) { return m.Income, m.YearAgoIncome }, okColor, errColor)
+
+	line()
+	title("Expense by month")
+	line()
+	trend.render(func(m CategoryTrend) ([]Amount, Amount) { return m.Expense, m.YearAgoExpense }, errColor, okColor)
+}
+
+// render displays one row per category, highlighting the months above and
+// below the average of the category.
+func (trend Trend) render(series func(CategoryTrend) ([]Amount, Amount), above, below *color.Color) {
+	headers := []string{"Category"}
+	for _, month := range trend.Months {
+		headers = append(headers, month.Format("Jan 06"))
+	}
+	headers = append(headers, "Average", "MoM", "YoY", "")
+
+	movements := make([]CategoryTrend, 0, len(trend.Movements))
+	for _, m := range trend.Movements {
+		if values, _ := series(m); total(values) > 0 {
+			movements = append(movements, m)
+		}
+	}
+	sort.Slice(movements, func(i, j int) bool {
+		left, _ := series(movements[i])
+		right, _ := series(movements[j])
+		return total(left) > total(right)
+	})
+
+	table := table()
+	table.SetHeader(headers)
+
+	for _, m := range movements {
+		values, yearAgo := series(m)
+		row := []string{trend.Categories[m.Category]}
+
+		for idx, v := range values {
+			switch deviation(values, idx) {
+			case 1:
+				row = append(row, above.Sprint(v))
+			case -1:
+				row = append(row, below.Sprint(v))
+			default:
+				row = append(row, v.String())
+			}
+		}
+
+		row = append(row, average(values).String(), percentChange(monthOverMonth(values)), percentChange(yearOverYear(values, yearAgo)), sparkline(values))
+		table.Append(row)
+	}
+
+	table.Render()
+}
+
+func percentChange(ratio float64, ok bool) string {
+	if !ok {
+		return ""
+	}
+
+	return fmt.Sprintf("%+.1f %%", ratio*100)
+}
+
+var sparks = []rune("▁▂▃▄▅▆▇█")
+
+func sparkline(values []Amount) string {
+	max := Amount(0)
+	for _, v := range values {
+		if v > max {
+			max = v
+		}
+	}
+
+	line := make([]rune, len(values))
+	for idx, v := range values {
+		line[idx] = sparks[0]
+		if max > 0 {
+			line[idx] = sparks[int(v*Amount(len(sparks)-1)/max)]
+		}
+	}
+
+	return string(line)
+}
+
+func (status BudgetStatus) Print(meta *Metadata) {
+	title(fmt.Sprintf("Budgets for %s", status.Month.Format("January 2006")))
+	attr("Day", fmt.Sprintf("%d of %d", status.Day, status.Days))
+
+	line()
+
+	progressLength := 40
+
+	table := table()
+	table.SetHeader([]string{"Category", "Budget", "Spent", "Remaining", "Projected", "Spent %", ""})
+
+	for _, b := range status.Budgets {
+		pct := b.Spent.Ratio(b.Limit) * 100
+		prog := strings.Repeat("▪", int(math.Min(pct, 100))/int(100/progressLength))
+
+		c := okColor
+		switch {
+		case b.Spent > b.Limit:
+			c = errColor
+		case status.Projected(b) > b.Limit:
+			c = warnColor
+		}
+
+		table.Append([]string{
+			status.Categories[b.Category],
+			b.Limit.String(),
+			b.Spent.String(),
+			c.Sprint(b.Remaining()),
+			c.Sprint(status.Projected(b)),
+			fmt.Sprintf("%.1f %%", pct),
+			c.Sprint(prog),
+		})
+	}
+
+	table.Render()
+
+	for _, b := range status.Budgets {
+		switch {
+		case b.Spent > b.Limit:
+			Warn(fmt.Errorf("the budget for '%s' is exceeded by %s", status.Categories[b.Category], -b.Remaining()))
+		case status.Projected(b) > b.Limit:
+			Warn(fmt.Errorf("at this pace, the budget for '%s' will be exceeded by %s at the end of the month", status.Categories[b.Category], status.Projected(b)-b.Limit))
+		}
+	}
+}
+
+func (plan EnvelopePlan) Print(meta *Metadata) {
+	title(fmt.Sprintf("Envelopes for %s", plan.Month.Format("January 2006")))
+	attr("Income", Curr(plan.Income, plan.Currency))
+	attr("Allocated", Curr(plan.Allocated(), plan.Currency))
+	if plan.Unallocated() < 0 {
+		attr("Unallocated", errColor.Sprint(Curr(plan.Unallocated(), plan.Currency)))
+	} else {
+		attr("Unallocated", okColor.Sprint(Curr(plan.Unallocated(), plan.Currency)))
+	}
+	attr("Main account", Curr(plan.Available, plan.Currency))
+
+	line()
+
+	table := table()
+	table.SetHeader([]string{"Envelope", "Target", "Spent", "Needed", "Balance", "Transfer"})
+
+	for _, envelope := range plan.Envelopes {
+		transfer := attrColor.Sprint(envelope.Transfer())
+		if envelope.Transfer() > 0 {
+			transfer = okColor.Sprintf("→ %s", envelope.Transfer())
+		}
+
+		table.Append([]string{
+			envelope.Space,
+			envelope.Target.String(),
+			envelope.Spent.String(),
+			envelope.Needed().String(),
+			envelope.Balance.String(),
+			transfer,
+		})
+	}
+
+	table.SetFooter([]string{"", "", "", "", "Total", Curr(plan.Transfers(), plan.Currency)})
+	table.Render()
+
+	if plan.Transfers() > plan.Available {
+		Warn(fmt.Errorf("your main account does not hold enough money to fund your envelopes"))
+	} else if plan.Transfers() > 0 {
+		Info("Run 'n26 envelopes fund' to perform these transfers.")
+	}
+}
+
+func (subscriptions SubscriptionList) Print(meta *Metadata) {
+	var monthly, yearly Amount
+	currency := ""
+
+	table := table()
+	table.SetHeader([]string{"Name", "Cadence", "Amount", "Monthly", "Yearly", "Last", "Next", ""})
+
+	for _, sub := range subscriptions {
+		notes := make([]string, 0)
+		if sub.PriceIncreased() {
+			notes = append(notes, warnColor.Sprintf("price up from %s", sub.PreviousAmount))
+		}
+		if sub.Missed > 0 {
+			notes = append(notes, errColor.Sprintf("%d missed", sub.Missed))
+		}
+
+		table.Append([]string{
+			sub.Name,
+			sub.Cadence,
+			Curr(sub.Amount, sub.Currency),
+			sub.Monthly.String(),
+			sub.Yearly.String(),
+			titleColor.Sprint(sub.Last.Format("02 Jan 2006")),
+			sub.Next.Format("02 Jan 2006"),
+			strings.Join(notes, ", "),
+		})
+
+		monthly += sub.Monthly
+		yearly += sub.Yearly
+		currency = sub.Currency
+	}
+
+	table.SetFooter([]string{"", "", "Total", Curr(monthly, currency), Curr(yearly, currency), "", "", ""})
+	table.Render()
+}
+
+func (forecast Forecast) Print(meta *Metadata) {
+	title("Balance forecast")
+	attr("Usable balance", Curr(forecast.Balance, forecast.Currency))
+	if len(forecast.Days) > 0 {
+		last := forecast.Days[len(forecast.Days)-1]
+		attr(fmt.Sprintf("Projected on %s", last.Date.Format("02 Jan 2006")), Curr(last.Balance, forecast.Currency))
+	}
+	attr("Threshold", Curr(forecast.Threshold, forecast.Currency))
+
+	line()
+	forecast.chart(12, 90)
+	line()
+
+	table := table()
+	table.SetHeader([]string{"Date", "Movement", "Amount", "Balance"})
+
+	for _, day := range forecast.Days {
+		for _, m := range day.Movements {
+			amount := okColor.Sprintf("← %s", m.Amount)
+			if m.Amount < 0 {
+				amount = errColor.Sprintf("→ %s", m.Amount.Abs())
+			}
+
+			balance := day.Balance.String()
+			if day.Balance < forecast.Threshold {
+				balance = errColor.Sprint(balance)
+			}
+
+			table.Append([]string{titleColor.Sprint(day.Date.Format("02 Jan 2006")), m.Name, amount, balance})
+		}
+	}
+
+	table.Render()
+
+	if day, ok := forecast.BelowThreshold(); ok {
+		Warn(fmt.Errorf("your usable balance is projected to drop below %s on %s", Curr(forecast.Threshold, forecast.Currency), day.Date.Format("02 Jan 2006")))
+	}
+}
+
+// chart draws the projected balance as bars, one column per day or, when
+// there are more days than columns, per group of days showing their lowest
+// balance. Days under the threshold are drawn in red.
+func (forecast Forecast) chart(height, width int) {
+	if len(forecast.Days) == 0 {
+		return
+	}
+
+	step := (len(forecast.Days) + width - 1) / width
+	values := make([]Amount, 0, width)
+	for idx := 0; idx < len(forecast.Days); idx += step {
+		end := idx + step
+		if end > len(forecast.Days) {
+			end = len(forecast.Days)
+		}
+
+		low := forecast.Days[idx].Balance
+		for _, day := range forecast.Days[idx:end] {
+			if day.Balance < low {
+				low = day.Balance
+			}
+		}
+		values = append(values, low)
+	}
+
+	lo, hi := forecast.Threshold, forecast.Threshold
+	for _, v := range values {
+		if v < lo {
+			lo = v
+		}
+		if v > hi {
+			hi = v
+		}
+	}
+	if hi == lo {
+		hi = lo + 1
+	}
+
+	level := func(v Amount) int {
+		return int((v - lo) * Amount(height-1) / (hi - lo))
+	}
+
+	for row := height - 1; row >= 0; row-- {
+		label := ""
+		switch row {
+		case height - 1:
+			label = hi.String()
+		case 0:
+			label = lo.String()
+		case level(forecast.Threshold):
+			label = forecast.Threshold.String()
+		}
+
+		fmt.Printf("  %10s ┤", label)
+		for _, v := range values {
+			switch {
+			case level(v) >= row && v < forecast.Threshold:
+				errColor.Print("█")
+			case level(v) >= row:
+				okColor.Print("█")
+			case row == level(forecast.Threshold):
+				attrColor.Print("┄")
+			default:
+				fmt.Print(" ")
+			}
+		}
+		line()
+	}
+
+	first, last := forecast.Days[0].Date.Format("02 Jan"), forecast.Days[len(forecast.Days)-1].Date.Format("02 Jan")
+	fmt.Printf("  %10s └%s\n", "", strings.Repeat("─", len(values)))
+	fmt.Printf("  %10s  %s%*s\n", "", first, int(math.Max(0, float64(len(values)-len(first)))), last)
+}
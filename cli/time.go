@@ -0,0 +1,31 @@
+package cli
+
+import (
+	"fmt"
+	"time"
+)
+
+// Location is the time zone periods are interpreted in and dates are
+// displayed in.
+var Location = time.Local
+
+func SetTimezone(name string) error {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return fmt.Errorf("unknown timezone '%s'", name)
+	}
+
+	Location = loc
+	return nil
+}
+
+// Millis converts a time to the milliseconds since the epoch used by the
+// N26 API.
+func Millis(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
+
+// FromMillis converts milliseconds since the epoch to a time in Location.
+func FromMillis(ms int64) time.Time {
+	return time.Unix(0, ms*int64(time.Millisecond)).In(Location)
+}
@@ -2,12 +2,51 @@ package cli
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/fatih/color"
 )
 
 type Metadata struct {
-	Categories map[string]string
+	Categories  map[string]string
+	Config      *Config
+	DryRun      bool
+	AutoConfirm bool
+}
+
+func (meta *Metadata) GetPolicy() Policy {
+	if meta != nil && meta.Config != nil {
+		return meta.Config.Policy
+	}
+	return Policy{}
+}
+
+func (meta *Metadata) GetLimits() Limits {
+	if meta != nil && meta.Config != nil {
+		return meta.Config.Limits
+	}
+	return Limits{}
+}
+
+func (meta *Metadata) GetBudgets() map[string]Amount {
+	if meta != nil && meta.Config != nil {
+		return meta.Config.Budgets
+	}
+	return nil
+}
+
+func (meta *Metadata) GetEnvelopes() []EnvelopeConfig {
+	if meta != nil && meta.Config != nil {
+		return meta.Config.Envelopes
+	}
+	return nil
+}
+
+func (meta *Metadata) GetForecastThreshold() Amount {
+	if meta != nil && meta.Config != nil {
+		return meta.Config.Forecast.Threshold
+	}
+	return 0
 }
 
 func (meta *Metadata) GetCategories() map[string]string {
@@ -31,8 +70,55 @@ type Printable interface {
 	JSON(meta *Metadata)
 }
 
+// CSVPrintable is implemented by the data that can also be exported as CSV.
+type CSVPrintable interface {
+	CSV(meta *Metadata)
+}
+
 type SimpleMessage string
 
+type DryRunList []DryRun
+
+type DryRun struct {
+	Method string      `json:"method"`
+	Path   string      `json:"path"`
+	Body   interface{} `json:"body,omitempty"`
+}
+
+type AuthStatus struct {
+	Profile       string    `json:"profile"`
+	Store         string    `json:"store"`
+	DeviceToken   string    `json:"device_token"`
+	LoggedIn      bool      `json:"logged_in"`
+	TokenType     string    `json:"token_type,omitempty"`
+	Expiry        time.Time `json:"expiry"`
+	Refreshable   bool      `json:"refreshable"`
+	RefreshExpiry time.Time `json:"refresh_expiry"`
+}
+
+type ProfileList []Profile
+
+type Profile struct {
+	Name    string `json:"name"`
+	Default bool   `json:"default"`
+}
+
+type AuditLog []AuditEntry
+
+type AuditEntry struct {
+	Timestamp    time.Time `json:"timestamp"`
+	Command      string    `json:"command"`
+	Action       string    `json:"action"`
+	Recipient    string    `json:"recipient,omitempty"`
+	Amount       Amount    `json:"amount"`
+	Currency     string    `json:"currency,omitempty"`
+	Request      DryRun    `json:"request"`
+	Status       int       `json:"status"`
+	Message      string    `json:"message"`
+	PreviousHash string    `json:"previous_hash"`
+	Hash         string    `json:"hash"`
+}
+
 type PersonalInformation struct {
 	ID              string `json:"id"`
 	Email           string `json:"email"`
@@ -52,9 +138,9 @@ type Account struct {
 }
 
 type Balance struct {
-	AvailableBalance float64 `json:"availableBalance"`
-	UsageBalance     float64 `json:"usableBalance"`
-	Currency         string  `json:"currency"`
+	AvailableBalance Amount `json:"availableBalance"`
+	UsageBalance     Amount `json:"usableBalance"`
+	Currency         string `json:"currency"`
 }
 
 type CardList []Card
@@ -99,8 +185,8 @@ var (
 type LimitList []Limit
 
 type Limit struct {
-	Limit  string  `json:"limit"`
-	Amount float64 `json:"amount"`
+	Limit  string `json:"limit"`
+	Amount Amount `json:"amount"`
 }
 
 var (
@@ -111,26 +197,50 @@ var (
 )
 
 type SpaceTransaction struct {
-	Amount      float64 `json:"amount"`
-	FromSpaceID string  `json:"fromSpaceId"`
-	ToSpaceID   string  `json:"toSpaceId"`
+	Amount      Amount `json:"amount"`
+	FromSpaceID string `json:"fromSpaceId"`
+	ToSpaceID   string `json:"toSpaceId"`
 }
 
 type PastTransactionList []PastTransaction
 
 type PastTransaction struct {
-	ID           string  `json:"id"`
-	Type         string  `json:"type"`
-	Date         int64   `json:"visibleTS"`
-	Amount       float64 `json:"amount"`
-	Currency     string  `json:"currencyCode"`
-	Partner      string  `json:"partnerName,omitempty"`
-	Pending      bool    `json:"pending"`
-	MerchantName string  `json:"merchantName"`
-	MerchantCity string  `json:"merchantCity"`
-	Comment      string  `json:"referenceText"`
-	Category     string  `json:"category"`
-	Scheme       string  `json:"paymentScheme"`
+	ID               string  `json:"id"`
+	Type             string  `json:"type"`
+	Date             int64   `json:"visibleTS"`
+	Amount           Amount  `json:"amount"`
+	Currency         string  `json:"currencyCode"`
+	OriginalAmount   Amount  `json:"originalAmount"`
+	OriginalCurrency string  `json:"originalCurrency"`
+	ExchangeRate     float64 `json:"exchangeRate"`
+	Partner          string  `json:"partnerName,omitempty"`
+	Pending          bool    `json:"pending"`
+	MerchantName     string  `json:"merchantName"`
+	MerchantCity     string  `json:"merchantCity"`
+	Comment          string  `json:"referenceText"`
+	Category         string  `json:"category"`
+	Scheme           string  `json:"paymentScheme"`
+	Report           *Money  `json:"-"`
+}
+
+// Foreign tells whether the transaction was made in another currency than
+// the one of the account.
+func (trx PastTransaction) Foreign() bool {
+	return trx.OriginalCurrency != "" && trx.OriginalCurrency != trx.Currency && trx.ExchangeRate > 0
+}
+
+// FXFee is the part of the amount exceeding the original amount converted
+// at the exchange rate of the transaction.
+func (trx PastTransaction) FXFee() Amount {
+	if !trx.Foreign() {
+		return 0
+	}
+
+	fee := trx.Amount.Abs() - trx.OriginalAmount.Abs().Div(trx.ExchangeRate).Round(trx.Currency)
+	if fee < 0 {
+		return 0
+	}
+	return fee
 }
 
 type MoneyBeam struct {
@@ -139,12 +249,32 @@ type MoneyBeam struct {
 }
 
 type MoneyBeamDetails struct {
-	Type         string  `json:"type"`
-	Amount       float64 `json:"amount"`
-	PartnerName  string  `json:"partnerName"`
-	PartnerEmail string  `json:"partnerEmail,omitempty"`
-	PartnerPhone string  `json:"partnerPhone,omitempty"`
-	Comment      string  `json:"referenceText,omitempty"`
+	Type         string `json:"type"`
+	Amount       Amount `json:"amount"`
+	PartnerName  string `json:"partnerName"`
+	PartnerEmail string `json:"partnerEmail,omitempty"`
+	PartnerPhone string `json:"partnerPhone,omitempty"`
+	PartnerIBAN  string `json:"partnerIban,omitempty"`
+	PartnerBIC   string `json:"partnerBic,omitempty"`
+	Comment      string `json:"referenceText,omitempty"`
+}
+
+type BatchTransferList []BatchTransfer
+
+type BatchTransfer struct {
+	Recipient string `json:"recipient"`
+	Name      string `json:"name"`
+	BIC       string `json:"bic"`
+	Amount    Amount `json:"amount"`
+	Reference string `json:"reference"`
+}
+
+func (batch BatchTransferList) Total() Amount {
+	var total Amount
+	for _, trx := range batch {
+		total += trx.Amount
+	}
+	return total
 }
 
 type MoneyBeamPartner struct {
@@ -154,7 +284,7 @@ type MoneyBeamPartner struct {
 }
 
 type Spaces struct {
-	Balance float64 `json:"totalBalance"`
+	Balance Amount  `json:"totalBalance"`
 	Spaces  []Space `json:"spaces"`
 }
 
@@ -163,11 +293,11 @@ type Space struct {
 	Name    string `json:"name"`
 	Primary bool   `json:"isPrimary"`
 	Balance struct {
-		AvailableBalance float64 `json:"availableBalance"`
-		Currency         string  `json:"currency"`
+		AvailableBalance Amount `json:"availableBalance"`
+		Currency         string `json:"currency"`
 	} `json:"balance"`
 	Goal struct {
-		Amount float64 `json:"amount"`
+		Amount Amount `json:"amount"`
 	}
 }
 
@@ -192,15 +322,265 @@ type Category struct {
 }
 
 type Statistics struct {
-	Categories   map[string]string `json:"-"`
-	Currency     string            `json:"-"`
-	From         int64             `json:"from"`
-	To           int64             `json:"to"`
-	TotalExpense float64           `json:"totalExpense"`
-	TotalIncome  float64           `json:"totalIncome"`
-	Movements    []struct {
-		Category string  `json:"id"`
-		Expense  float64 `json:"expense"`
-		Income   float64 `json:"income"`
-	} `json:"items"`
+	Categories   map[string]string   `json:"-"`
+	Currency     string              `json:"-"`
+	From         int64               `json:"from"`
+	To           int64               `json:"to"`
+	TotalExpense Amount              `json:"totalExpense"`
+	TotalIncome  Amount              `json:"totalIncome"`
+	FXFees       Amount              `json:"-"`
+	Currencies   []CurrencyTotal     `json:"-"`
+	Transactions PastTransactionList `json:"-"`
+	Movements    []CategoryMovement  `json:"items"`
+}
+
+type CategoryMovement struct {
+	Category string `json:"id"`
+	Expense  Amount `json:"expense"`
+	Income   Amount `json:"income"`
+}
+
+// CurrencyTotal sums the movements made in a given currency, in that
+// currency.
+type CurrencyTotal struct {
+	Currency     string
+	Transactions int
+	Income       Amount
+	Expense      Amount
+	FXFees       Amount
+}
+
+// Trend holds the movements of each category over consecutive months.
+type Trend struct {
+	Categories map[string]string
+	Months     []time.Time
+	Movements  []CategoryTrend
+}
+
+// CategoryTrend holds the monthly income and expense of a category, and
+// those of the same month one year before the last one.
+type CategoryTrend struct {
+	Category       string
+	Income         []Amount
+	Expense        []Amount
+	YearAgoIncome  Amount
+	YearAgoExpense Amount
+}
+
+// deviationThreshold is how far from the average of its category a monthly
+// amount must be to be highlighted, as a fraction of that average.
+const deviationThreshold = 0.5
+
+func total(values []Amount) Amount {
+	var total Amount
+	for _, v := range values {
+		total += v
+	}
+	return total
+}
+
+func average(values []Amount) Amount {
+	if len(values) == 0 {
+		return 0
+	}
+	return (total(values) / Amount(len(values))).Round("")
+}
+
+// change is the variation of an amount compared to a previous one, as a
+// fraction of the latter.
+func change(current, previous Amount) (float64, bool) {
+	if previous == 0 {
+		return 0, false
+	}
+
+	return current.Ratio(previous) - 1, true
+}
+
+// monthOverMonth is the variation of the last amount of a series compared to
+// the one before it.
+func monthOverMonth(values []Amount) (float64, bool) {
+	if len(values) < 2 {
+		return 0, false
+	}
+
+	return change(values[len(values)-1], values[len(values)-2])
+}
+
+// yearOverYear is the variation of the last amount of a series compared to
+// the same month of the previous year.
+func yearOverYear(values []Amount, yearAgo Amount) (float64, bool) {
+	if len(values) == 0 {
+		return 0, false
+	}
+
+	return change(values[len(values)-1], yearAgo)
+}
+
+// deviation tells whether a monthly amount is significantly above (1) or
+// below (-1) the average of its series.
+func deviation(values []Amount, idx int) int {
+	avg := average(values)
+	if avg == 0 {
+		return 0
+	}
+
+	switch ratio := values[idx].Ratio(avg); {
+	case ratio > 1+deviationThreshold:
+		return 1
+	case ratio < 1-deviationThreshold:
+		return -1
+	}
+	return 0
+}
+
+// BudgetStatus compares the monthly budget of each category to what was
+// spent so far this month.
+type BudgetStatus struct {
+	Categories map[string]string
+	Month      time.Time
+	Day        int
+	Days       int
+	Budgets    []Budget
+}
+
+type Budget struct {
+	Category string
+	Limit    Amount
+	Spent    Amount
+}
+
+func (budget Budget) Remaining() Amount {
+	return budget.Limit - budget.Spent
+}
+
+// Projected extrapolates what will be spent by the end of the month from
+// what was spent so far.
+func (status BudgetStatus) Projected(budget Budget) Amount {
+	if status.Day == 0 {
+		return budget.Spent
+	}
+	return (budget.Spent * Amount(status.Days) / Amount(status.Day)).Round("")
+}
+
+// EnvelopePlan lists what each envelope should receive this month, and the
+// transfers from the main account needed to fund them.
+type EnvelopePlan struct {
+	Month       time.Time
+	MainSpaceID string
+	Currency    string
+	Income      Amount
+	Available   Amount
+	Envelopes   []Envelope
+}
+
+type Envelope struct {
+	SpaceID string
+	Space   string
+	Target  Amount
+	Spent   Amount
+	Balance Amount
+}
+
+// Needed is what the envelope should still hold this month.
+func (envelope Envelope) Needed() Amount {
+	if envelope.Spent > envelope.Target {
+		return 0
+	}
+	return envelope.Target - envelope.Spent
+}
+
+// Transfer is the amount to move to the envelope so it holds what it needs.
+func (envelope Envelope) Transfer() Amount {
+	if envelope.Balance > envelope.Needed() {
+		return 0
+	}
+	return envelope.Needed() - envelope.Balance
+}
+
+func (plan EnvelopePlan) Allocated() Amount {
+	var total Amount
+	for _, envelope := range plan.Envelopes {
+		total += envelope.Target
+	}
+	return total
+}
+
+func (plan EnvelopePlan) Transfers() Amount {
+	var total Amount
+	for _, envelope := range plan.Envelopes {
+		total += envelope.Transfer()
+	}
+	return total
+}
+
+// Unallocated is the income of the month not assigned to any envelope.
+func (plan EnvelopePlan) Unallocated() Amount {
+	return plan.Income - plan.Allocated()
+}
+
+type SubscriptionList []Subscription
+
+// Subscription is a payment detected as recurring. Missed counts the
+// payments that were expected but did not happen, including overdue ones.
+type Subscription struct {
+	Name           string
+	Cadence        string
+	Occurrences    int
+	Amount         Amount
+	PreviousAmount Amount
+	Currency       string
+	Last           time.Time
+	Next           time.Time
+	Monthly        Amount
+	Yearly         Amount
+	Missed         int
+}
+
+func (sub Subscription) PriceIncreased() bool {
+	return sub.Amount > sub.PreviousAmount
+}
+
+type StandingOrders struct {
+	Data []StandingOrder `json:"data"`
+}
+
+type StandingOrder struct {
+	ID          string `json:"id"`
+	Amount      Amount `json:"amount"`
+	Currency    string `json:"currencyCode"`
+	PartnerName string `json:"partnerName"`
+	Frequency   string `json:"executionFrequency"`
+	Next        int64  `json:"nextExecutingTS"`
+	Stop        int64  `json:"stopTS"`
+}
+
+// Forecast projects the usable balance of the account for each of the next
+// days, from the expected recurring movements.
+type Forecast struct {
+	Currency  string
+	Balance   Amount
+	Threshold Amount
+	Days      []ForecastDay
+}
+
+type ForecastDay struct {
+	Date      time.Time
+	Balance   Amount
+	Movements []ForecastMovement
+}
+
+type ForecastMovement struct {
+	Name   string
+	Amount Amount
+}
+
+// BelowThreshold returns the first day the balance is projected to be under
+// the threshold.
+func (forecast Forecast) BelowThreshold() (ForecastDay, bool) {
+	for _, day := range forecast.Days {
+		if day.Balance < forecast.Threshold {
+			return day, true
+		}
+	}
+	return ForecastDay{}, false
 }
@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/alecthomas/kingpin"
 	"github.com/apognu/n26/api"
@@ -14,15 +15,37 @@ func main() {
 	kp.HelpFlag.Short('h')
 	kp.UsageTemplate(kingpin.DefaultUsageTemplate)
 
-	kpFormat := kp.Flag("format", "how to display data").Short('o').Default("pretty").Enum("pretty", "json")
+	kpFormat := kp.Flag("format", "how to display data (csv is only supported by stats trend)").Short('o').Default("pretty").Enum("pretty", "json", "csv")
+	kpProfile := kp.Flag("profile", "profile to use (defaults to the default profile)").Envar("N26_PROFILE").String()
+	kpUsername := kp.Flag("username", "N26 email address to log in with").Envar("N26_USERNAME").String()
+	kpPasswordCmd := kp.Flag("password-cmd", "command printing the N26 password to log in with").String()
+	kpPasswordFD := kp.Flag("password-fd", "file descriptor to read the N26 password to log in with from").Default("-1").Int()
+	kpPassphraseFD := kp.Flag("passphrase-fd", "file descriptor to read the credentials passphrase from").Default("-1").Int()
+	kpYes := kp.Flag("yes", "approve money movements allowed by the confirmation policy without asking").Short('y').Bool()
+	kpDryRun := kp.Flag("dry-run", "validate money movements and display the requests instead of sending them").Bool()
+	kpTimezone := kp.Flag("timezone", "timezone to interpret periods and display dates in (e.g. Europe/Berlin)").Envar("N26_TIMEZONE").String()
+	kpReportCurrency := kp.Flag("report-currency", "currency to report balances, spaces, transactions and statistics in").String()
 
 	kpInfo := kp.Command("info", "Display the account holder personal information")
 	kpAccount := kp.Command("account", "Display the account information")
 	kpBalance := kp.Command("balance", "Display the account current balance")
 
 	kpStats := kp.Command("stats", "Get income and expense statistics")
-	kpStatsFrom := kpStats.Flag("from", "Date to start statistics from (e.g. 2018-01-01)").String()
-	kpStatsTo := kpStats.Flag("to", "Date to end statistics at (e.g. 2018-01-31)").String()
+	kpStatsFrom := kpStats.Flag("from", "Date to start statistics from (e.g. 2018-01-01, 2018-Q1, last-month, 30d-ago)").String()
+	kpStatsTo := kpStats.Flag("to", "Date to end statistics at, included (e.g. 2018-01-31, 2018-01, yesterday)").String()
+	kpStatsShow := kpStats.Command("show", "Get income and expense statistics over a period").Default()
+	kpStatsTrend := kpStats.Command("trend", "Compare income and expense by category over the last months")
+	kpStatsTrendMonths := kpStatsTrend.Flag("months", "number of months to compare, including the current one").Short('m').Default("12").Int()
+
+	kpBudget := kp.Command("budget", "Track your monthly budgets")
+	kpBudgetStatus := kpBudget.Command("status", "Compare your budgets to the expenses of the current month")
+
+	kpEnvelopes := kp.Command("envelopes", "Fund spaces used as envelopes from your monthly income")
+	kpEnvelopesPlan := kpEnvelopes.Command("plan", "Display what each envelope should receive this month").Default()
+	kpEnvelopesFund := kpEnvelopes.Command("fund", "Transfer the money needed by each envelope to its space")
+
+	kpForecast := kp.Command("forecast", "Project your balance from your recurring movements")
+	kpForecastDays := kpForecast.Flag("days", "number of days to project").Short('d').Default("30").Int()
 
 	kpSpaces := kp.Command("spaces", "Manage your spaces")
 	kpSpacesList := kpSpaces.Command("list", "List your spaces and their balances")
@@ -30,7 +53,7 @@ func main() {
 	kpSpacesTransfer := kpSpaces.Command("transfer", "Transfer money from one space to another")
 	kpSpacesTransferFrom := kpSpacesTransfer.Arg("source", "ID or name of the source space").Required().String()
 	kpSpacesTransferTo := kpSpacesTransfer.Arg("destination", "ID or name of the destination space").Required().String()
-	kpSpacesTransferAmount := kpSpacesTransfer.Arg("amount", "amount of money to transfer").Required().Float64()
+	kpSpacesTransferAmount := amount(kpSpacesTransfer.Arg("amount", "amount of money to transfer").Required())
 
 	kpCards := kp.Command("cards", "Display the cards linked to your account")
 	kpCardsList := kpCards.Command("list", "Display the cards linked to your account")
@@ -38,28 +61,164 @@ func main() {
 
 	kpTransactions := kp.Command("transactions", "Manage your transactions")
 	kpTransactionsList := kpTransactions.Command("list", "List your past transactions")
-	kpTransactionsFrom := kpTransactions.Flag("from", "date from which to list transactions").String()
-	kpTransactionsTo := kpTransactions.Flag("to", "date to which to list transactions").String()
+	kpTransactionsFrom := kpTransactions.Flag("from", "date from which to list transactions (e.g. 2018-01-01, 2018-Q1, last-month, 30d-ago)").String()
+	kpTransactionsTo := kpTransactions.Flag("to", "date to which to list transactions, included (e.g. 2018-01-31, 2018-01, yesterday)").String()
 	kpTransactionsLimit := kpTransactions.Flag("limit", "number of transactions to display").Short('l').Default("50").Int()
 
 	kpMoneyBeam := kpTransactions.Command("beam", "Create a Money Beam")
 	kpMoneyBeamRecipient := kpMoneyBeam.Arg("recipient", "email or phone number of the recipient").Required().String()
 	kpMoneyBeamName := kpMoneyBeam.Flag("name", "name of the recipient").Short('n').String()
-	kpMoneyBeamAmount := kpMoneyBeam.Arg("amount", "amount to transfer").Required().Float64()
+	kpMoneyBeamAmount := amount(kpMoneyBeam.Arg("amount", "amount to transfer").Required())
 	kpMoneyBeamComment := kpMoneyBeam.Flag("comment", "comment to add to the transfer").Short('c').String()
 
+	kpSubscriptions := kp.Command("subscriptions", "Detect your recurring payments")
+	kpSubscriptionsList := kpSubscriptions.Command("list", "List the recurring payments found in your transactions")
+	kpSubscriptionsMonths := kpSubscriptionsList.Flag("months", "number of months of transactions to analyse").Short('m').Default("12").Int()
+
+	kpTransfer := kp.Command("transfer", "Transfer money to other people")
+	kpTransferBatch := kpTransfer.Command("batch", "Perform transfers listed in a CSV or JSON file")
+	kpTransferBatchFile := kpTransferBatch.Arg("file", "file listing recipient, amount, reference and optional name and BIC").Required().ExistingFile()
+	kpTransferBatchID := kpTransferBatch.Flag("id", "name under which the progress of the batch is recorded (defaults to one per file)").String()
+	kpTransferBatchRestart := kpTransferBatch.Flag("restart", "discard the recorded progress and perform every transfer again").Bool()
+
+	kpAudit := kp.Command("audit", "Inspect the local log of money movements")
+	kpAuditList := kpAudit.Command("list", "List the recorded money movements")
+	kpAuditVerify := kpAudit.Command("verify", "Verify the audit log has not been tampered with")
+
+	kpAuth := kp.Command("auth", "Manage your authentication to N26")
+	kpAuthLogin := kpAuth.Command("login", "Log in to N26, replacing the current session")
+	kpAuthLogout := kpAuth.Command("logout", "Log out of N26 and delete the local credentials")
+	kpAuthStatus := kpAuth.Command("status", "Display information about the current session")
+
+	kpProfiles := kp.Command("profiles", "Manage profiles for several N26 accounts")
+	kpProfilesList := kpProfiles.Command("list", "List the existing profiles")
+	kpProfilesAdd := kpProfiles.Command("add", "Create a new profile")
+	kpProfilesAddName := kpProfilesAdd.Arg("name", "name of the profile").Required().String()
+	kpProfilesRemove := kpProfiles.Command("remove", "Remove a profile and its credentials")
+	kpProfilesRemoveName := kpProfilesRemove.Arg("name", "name of the profile").Required().String()
+	kpProfilesDefault := kpProfiles.Command("default", "Select the profile used by default")
+	kpProfilesDefaultName := kpProfilesDefault.Arg("name", "name of the profile").Required().String()
+
 	args := kingpin.MustParse(kp.Parse(os.Args[1:]))
 
-	cl, err := api.NewClient()
-	if err != nil {
-		cli.Fatal(fmt.Errorf("could not authenticate to N26"))
+	if *kpFormat == "csv" && args != kpStatsTrend.FullCommand() {
+		cli.Fatal(fmt.Errorf("--format csv is only supported by stats trend"))
+	}
+
+	if args == kpStatsTrend.FullCommand() && (*kpStatsFrom != "" || *kpStatsTo != "") {
+		cli.Fatal(fmt.Errorf("stats trend does not support --from and --to, use --months instead"))
+	}
+
+	if *kpReportCurrency != "" {
+		switch args {
+		case kpBalance.FullCommand(), kpSpacesList.FullCommand(), kpTransactionsList.FullCommand(), kpStatsShow.FullCommand():
+		default:
+			cli.Fatal(fmt.Errorf("--report-currency is not supported for this command"))
+		}
+	}
+
+	if *kpTimezone != "" {
+		if err := cli.SetTimezone(*kpTimezone); err != nil {
+			cli.Fatal(err)
+		}
+	}
+
+	if *kpPassphraseFD >= 0 {
+		if err := api.SetPassphraseFD(*kpPassphraseFD); err != nil {
+			cli.Fatal(err)
+		}
+	}
+
+	switch {
+	case *kpPasswordCmd != "":
+		api.SetCredentialsProvider(api.CommandCredentials(*kpUsername, *kpPasswordCmd))
+	case *kpPasswordFD >= 0:
+		api.SetCredentialsProvider(api.FileDescriptorCredentials(*kpUsername, *kpPasswordFD))
+	default:
+		api.SetCredentialsProvider(api.EnvCredentials(*kpUsername))
 	}
 
-	categories, _ := cl.GetCategories()
 	meta := &cli.Metadata{
-		Categories: categories,
+		DryRun:      *kpDryRun,
+		AutoConfirm: *kpYes,
+	}
+
+	// Only the command and flags that cannot hold secrets are audited.
+	audited := []string{"n26", args}
+	if *kpProfile != "" {
+		audited = append(audited, "--profile", *kpProfile)
+	}
+	if *kpYes {
+		audited = append(audited, "--yes")
+	}
+	if *kpTransferBatchID != "" {
+		audited = append(audited, "--id", *kpTransferBatchID)
+	}
+	if *kpTransferBatchRestart {
+		audited = append(audited, "--restart")
+	}
+	api.SetAuditCommand(strings.Join(audited, " "))
+
+	switch args {
+	case kpProfilesList.FullCommand():
+		cmd, err := api.GetProfiles()
+		display(meta, *kpFormat, cmd, err)
+		return
+	case kpProfilesAdd.FullCommand():
+		cmd, err := api.AddProfile(*kpProfilesAddName)
+		display(meta, *kpFormat, cmd, err)
+		return
+	case kpProfilesRemove.FullCommand():
+		cmd, err := api.RemoveProfile(*kpProfilesRemoveName)
+		display(meta, *kpFormat, cmd, err)
+		return
+	case kpProfilesDefault.FullCommand():
+		cmd, err := api.SetDefaultProfile(*kpProfilesDefaultName)
+		display(meta, *kpFormat, cmd, err)
+		return
+	}
+
+	if err := api.UseProfile(*kpProfile); err != nil {
+		cli.Fatal(err)
+	}
+
+	conf, err := api.LoadConfig()
+	if err != nil {
+		cli.Fatal(err)
 	}
 
+	meta.Config = conf
+
+	switch args {
+	case kpAuthLogin.FullCommand():
+		cmd, err := api.Login()
+		display(meta, *kpFormat, cmd, err)
+		return
+	case kpAuthLogout.FullCommand():
+		cmd, err := api.Logout()
+		display(meta, *kpFormat, cmd, err)
+		return
+	case kpAuthStatus.FullCommand():
+		cmd, err := api.GetAuthStatus()
+		display(meta, *kpFormat, cmd, err)
+		return
+	case kpAuditList.FullCommand():
+		cmd, err := api.GetAuditLog()
+		display(meta, *kpFormat, cmd, err)
+		return
+	case kpAuditVerify.FullCommand():
+		cmd, err := api.VerifyAuditLog()
+		display(meta, *kpFormat, cmd, err)
+		return
+	}
+
+	cl, err := api.NewClient()
+	if err != nil {
+		cli.Fatal(fmt.Errorf("could not authenticate to N26: %w", err))
+	}
+
+	meta.Categories, _ = cl.GetCategories()
+
 	var cmd cli.Printable
 
 	switch args {
@@ -69,8 +228,18 @@ func main() {
 		cmd, err = cl.GetAccount(meta)
 	case kpBalance.FullCommand():
 		cmd, err = cl.GetBalance(meta)
-	case kpStats.FullCommand():
+	case kpStatsShow.FullCommand():
 		cmd, err = cl.GetStatistics(meta, *kpStatsFrom, *kpStatsTo)
+	case kpStatsTrend.FullCommand():
+		cmd, err = cl.GetStatisticsTrend(meta, *kpStatsTrendMonths)
+	case kpBudgetStatus.FullCommand():
+		cmd, err = cl.GetBudgetStatus(meta)
+	case kpEnvelopesPlan.FullCommand():
+		cmd, err = cl.GetEnvelopePlan(meta)
+	case kpEnvelopesFund.FullCommand():
+		cmd, err = cl.FundEnvelopes(meta)
+	case kpForecast.FullCommand():
+		cmd, err = cl.GetForecast(meta, *kpForecastDays)
 	case kpCardsList.FullCommand():
 		cmd, err = cl.GetCards(meta)
 	case kpCardLimits.FullCommand():
@@ -79,23 +248,47 @@ func main() {
 		cmd, err = cl.GetPastTransactions(meta, *kpTransactionsFrom, *kpTransactionsTo, *kpTransactionsLimit)
 	case kpMoneyBeam.FullCommand():
 		cmd, err = cl.CreateMoneyBeam(meta, *kpMoneyBeamName, *kpMoneyBeamRecipient, *kpMoneyBeamAmount, *kpMoneyBeamComment)
+	case kpSubscriptionsList.FullCommand():
+		cmd, err = cl.GetSubscriptions(meta, *kpSubscriptionsMonths)
+	case kpTransferBatch.FullCommand():
+		cmd, err = cl.CreateBatchTransfer(meta, *kpTransferBatchFile, *kpTransferBatchID, *kpTransferBatchRestart)
 	case kpSpacesList.FullCommand():
 		cmd, err = cl.GetSpaces(meta)
 	case kpSpacesTransfer.FullCommand():
 		cmd, err = cl.CreateSpaceTransfer(meta, *kpSpacesTransferFrom, *kpSpacesTransferTo, *kpSpacesTransferAmount)
 	}
 
+	if err == nil && *kpReportCurrency != "" {
+		cmd, err = api.Report(cmd, *kpReportCurrency)
+	}
+
+	display(meta, *kpFormat, cmd, err)
+}
+
+func amount(s kingpin.Settings) *cli.Amount {
+	amount := new(cli.Amount)
+	s.SetValue(amount)
+	return amount
+}
+
+func display(meta *cli.Metadata, format string, cmd cli.Printable, err error) {
 	if err != nil {
 		cli.Fatal(err)
 		return
 	}
 
 	if cmd != nil {
-		switch *kpFormat {
+		switch format {
 		case "pretty":
 			cmd.Print(meta)
 		case "json":
 			cmd.JSON(meta)
+		case "csv":
+			if c, ok := cmd.(cli.CSVPrintable); ok {
+				c.CSV(meta)
+			} else {
+				cli.Fatal(fmt.Errorf("this command cannot be exported as CSV"))
+			}
 		}
 	}
 }